@@ -0,0 +1,226 @@
+package kraken
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DivisionPrecision is the number of decimal places of the quotients given by
+// Decimal.Div.
+var DivisionPrecision int32 = 16
+
+// maxDecimalExponent bounds the exponent accepted by ParseDecimal, so that
+// 1e1000000000 is not expanded to a billion digits.
+const maxDecimalExponent = 1000
+
+// Decimal is an exact, arbitrary precision decimal number, used for the prices
+// and volumes given by Kraken as strings. It keeps the number of decimal
+// places it was given with, so that it is written back exactly as read:
+// 1425.26000 stays 1425.26000. The zero value is 0.
+type Decimal struct {
+	// value is the unscaled value, nil meaning 0.
+	value *big.Int
+	// scale is the number of decimal places, never negative.
+	scale int32
+}
+
+// NewDecimal returns value * 10^-scale, e.g. NewDecimal(142526, 2) is 1425.26.
+func NewDecimal(value int64, scale int32) Decimal {
+	return newDecimal(big.NewInt(value), scale)
+}
+
+// newDecimal returns value * 10^-scale, keeping value.
+func newDecimal(value *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{value: new(big.Int).Mul(value, pow10(-scale))}
+	}
+	return Decimal{value: value, scale: scale}
+}
+
+// ParseDecimal parses a decimal number, e.g. 1425.26000, -0.5 or 1.2e-5.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		exp, err = strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil || exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return Decimal{}, errors.New("JSON Error: Invalid decimal " + strconv.Quote(s))
+		}
+		mantissa = s[:i]
+	}
+
+	digits, scale := mantissa, int64(0)
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		digits = mantissa[:i] + mantissa[i+1:]
+		scale = int64(len(mantissa) - i - 1)
+	}
+	unsigned := strings.TrimLeft(digits, "+-")
+	if len(digits)-len(unsigned) > 1 || unsigned == "" || strings.Trim(unsigned, "0123456789") != "" {
+		return Decimal{}, errors.New("JSON Error: Invalid decimal " + strconv.Quote(s))
+	}
+
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok || scale-exp > math.MaxInt32 {
+		return Decimal{}, errors.New("JSON Error: Invalid decimal " + strconv.Quote(s))
+	}
+	return newDecimal(value, int32(scale-exp)), nil
+}
+
+// pow10 returns 10^n.
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// int returns the unscaled value of d.
+func (d Decimal) int() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// rescale returns the unscaled value of d with the given scale, not lower
+// than the one of d.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.int()
+	}
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+// align returns the unscaled values of d and e with their common scale.
+func (d Decimal) align(e Decimal) (*big.Int, *big.Int, int32) {
+	scale := d.scale
+	if e.scale > scale {
+		scale = e.scale
+	}
+	return d.rescale(scale), e.rescale(scale), scale
+}
+
+// Scale returns the number of decimal places of d.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	x, y, scale := d.align(e)
+	return Decimal{value: new(big.Int).Add(x, y), scale: scale}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	x, y, scale := d.align(e)
+	return Decimal{value: new(big.Int).Sub(x, y), scale: scale}
+}
+
+// Mul returns d * e.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Div returns d / e rounded to DivisionPrecision decimal places.
+// It panics if e is zero.
+func (d Decimal) Div(e Decimal) Decimal {
+	if e.Sign() == 0 {
+		panic("kraken: division by zero")
+	}
+	// d / e = (d.value * 10^e.scale) / (e.value * 10^d.scale),
+	// computed with one more place to be rounded
+	num := new(big.Int).Mul(d.int(), pow10(e.scale+DivisionPrecision+1))
+	den := new(big.Int).Mul(e.int(), pow10(d.scale))
+	return Decimal{value: num.Quo(num, den), scale: DivisionPrecision + 1}.Round(DivisionPrecision)
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{value: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Round returns d rounded to the given number of decimal places, half away
+// from zero, e.g. 1.2345 rounded to 3 places is 1.235 and -1.2345 is -1.235.
+// The result has exactly places decimal places: 1.5 rounded to 3 is 1.500.
+func (d Decimal) Round(places int32) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if places >= d.scale {
+		return Decimal{value: d.rescale(places), scale: places}
+	}
+
+	unit := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.int(), unit, new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(unit) >= 0 {
+		q.Add(q, big.NewInt(int64(d.Sign())))
+	}
+	return Decimal{value: q, scale: places}
+}
+
+// Cmp compares d and e, returning -1 if d < e, 0 if d == e and +1 if d > e.
+// The number of decimal places does not matter: 1.50 equals 1.5.
+func (d Decimal) Cmp(e Decimal) int {
+	x, y, _ := d.align(e)
+	return x.Cmp(y)
+}
+
+// Sign returns -1 if d < 0, 0 if d == 0 and +1 if d > 0.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String returns d with all its decimal places, e.g. 1425.26000.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		i := len(digits) - int(d.scale)
+		digits = digits[:i] + "." + digits[i:]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON writes d as a JSON string, the format used by Kraken.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON reads d from a JSON string or number. Null and the empty
+// string give 0.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*d = Decimal{}
+		return nil
+	}
+	if len(b) >= 2 && b[0] == '"' && b[len(b)-1] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	if len(b) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
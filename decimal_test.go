@@ -0,0 +1,143 @@
+package kraken
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testDecimal(t *testing.T, s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func Test_ParseDecimal(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected string
+	}{
+		{"1425.26000", "1425.26000"},
+		{"0.00000001", "0.00000001"},
+		{"-0.5", "-0.5"},
+		{"+12", "12"},
+		{".5", "0.5"},
+		{"100", "100"},
+		{"1.2e-5", "0.000012"},
+		{"1.5E2", "150"},
+		{"0", "0"},
+		{"1e1000", "1" + strings.Repeat("0", 1000)},
+		{"1e-1000", "0." + strings.Repeat("0", 999) + "1"},
+	}
+	for _, tc := range testCases {
+		if d := testDecimal(t, tc.raw); d.String() != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.raw, tc.expected, d)
+		}
+	}
+
+	for _, raw := range []string{"", "-", "1.2.3", "abc", "1e", "--1", "1-2", "0x10", "1e2000000000", "1e1001", "1e-1001"} {
+		if _, err := ParseDecimal(raw); err == nil {
+			t.Errorf("%q should not parse", raw)
+		}
+	}
+}
+
+func Test_Decimal_JSON(t *testing.T) {
+	raw := `["1425.26000","0.10000000","-3",""]`
+	var ds []Decimal
+	if err := json.Unmarshal([]byte(raw), &ds); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `["1425.26000","0.10000000","-3","0"]` {
+		t.Errorf("Unexpected round trip: %s", b)
+	}
+
+	var d Decimal
+	if err := json.Unmarshal([]byte(`1425.5`), &d); err != nil || d.String() != "1425.5" {
+		t.Errorf("A JSON number should be accepted, got %v %v", d, err)
+	}
+	for _, raw := range []string{`"1,5"`, `"1.5`, `1.5"`, `"""1.5"`, `"null"`} {
+		if err := d.UnmarshalJSON([]byte(raw)); err == nil {
+			t.Errorf("%s should not be accepted", raw)
+		}
+	}
+}
+
+func Test_Decimal_Arithmetic(t *testing.T) {
+	a := testDecimal(t, "1425.26000")
+	b := testDecimal(t, "0.1")
+	testCases := []struct {
+		name     string
+		result   Decimal
+		expected string
+	}{
+		{"add", a.Add(b), "1425.36000"},
+		{"sub", b.Sub(a), "-1425.16000"},
+		{"mul", a.Mul(b), "142.526000"},
+		{"div", testDecimal(t, "1").Div(testDecimal(t, "3")), "0.3333333333333333"},
+		{"div rounded", testDecimal(t, "2").Div(testDecimal(t, "3")), "0.6666666666666667"},
+		{"div exact", testDecimal(t, "37500.0").Div(testDecimal(t, "0.25")), "150000.0000000000000000"},
+		{"neg", a.Neg(), "-1425.26000"},
+		{"zero add", Decimal{}.Add(b), "0.1"},
+		{"exact", testDecimal(t, "0.1").Add(testDecimal(t, "0.2")), "0.3"},
+	}
+	for _, tc := range testCases {
+		if tc.result.String() != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, tc.result)
+		}
+	}
+	if a.String() != "1425.26000" || b.String() != "0.1" {
+		t.Errorf("Operands should not change, got %s and %s", a, b)
+	}
+}
+
+func Test_Decimal_Round(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		places   int32
+		expected string
+	}{
+		{"1.2345", 3, "1.235"},
+		{"-1.2345", 3, "-1.235"},
+		{"1.2344", 3, "1.234"},
+		{"1.5", 3, "1.500"},
+		{"0.5", 0, "1"},
+		{"-0.49", 0, "0"},
+		{"37500.05", 1, "37500.1"},
+		{"99.99", 1, "100.0"},
+	}
+	for _, tc := range testCases {
+		if d := testDecimal(t, tc.raw).Round(tc.places); d.String() != tc.expected {
+			t.Errorf("%s rounded to %d: expected %s, got %s", tc.raw, tc.places, tc.expected, d)
+		}
+	}
+}
+
+func Test_Decimal_Cmp(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.50", "1.5", 0},
+		{"1.49", "1.5", -1},
+		{"-1", "-2", 1},
+		{"0", "0.000", 0},
+	}
+	for _, tc := range testCases {
+		if c := testDecimal(t, tc.a).Cmp(testDecimal(t, tc.b)); c != tc.expected {
+			t.Errorf("%s cmp %s: expected %d, got %d", tc.a, tc.b, tc.expected, c)
+		}
+	}
+	if !(Decimal{}).IsZero() || NewDecimal(142526, 2).String() != "1425.26" || NewDecimal(15, -2).String() != "1500" {
+		t.Error("Unexpected constructed decimals")
+	}
+	if f := testDecimal(t, "1425.26").Float64(); f != 1425.26 {
+		t.Errorf("Float64 expected 1425.26, got %v", f)
+	}
+}
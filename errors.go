@@ -0,0 +1,228 @@
+package kraken
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* Severities of the API messages. */
+const (
+	SeverityError   = "E"
+	SeverityWarning = "W"
+)
+
+/* Categories of the API messages. */
+const (
+	CategoryGeneral = "General"
+	CategoryAPI     = "API"
+	CategoryQuery   = "Query"
+	CategoryOrder   = "Order"
+	CategoryTrade   = "Trade"
+	CategoryFunding = "Funding"
+	CategoryService = "Service"
+	CategorySession = "Session"
+)
+
+// Sentinel errors, to be checked with errors.Is on the errors returned by the
+// API calls. A sentinel also matches the messages adding details to it,
+// e.g. ErrInvalidArguments matches EGeneral:Invalid arguments:volume.
+var (
+	ErrInvalidArguments    = ParseError("EGeneral:Invalid arguments")
+	ErrPermissionDenied    = ParseError("EGeneral:Permission denied")
+	ErrTemporaryLockout    = ParseError("EGeneral:Temporary lockout")
+	ErrInternalError       = ParseError("EGeneral:Internal error")
+	ErrRateLimitExceeded   = ParseError("EAPI:Rate limit exceeded")
+	ErrInvalidKey          = ParseError("EAPI:Invalid key")
+	ErrInvalidSignature    = ParseError("EAPI:Invalid signature")
+	ErrInvalidNonce        = ParseError("EAPI:Invalid nonce")
+	ErrUnknownAssetPair    = ParseError("EQuery:Unknown asset pair")
+	ErrUnknownAsset        = ParseError("EQuery:Unknown asset")
+	ErrInsufficientFunds   = ParseError("EOrder:Insufficient funds")
+	ErrOrderRateLimit      = ParseError("EOrder:Rate limit exceeded")
+	ErrOrdersLimitExceeded = ParseError("EOrder:Orders limit exceeded")
+	ErrUnknownOrder        = ParseError("EOrder:Unknown order")
+	ErrInvalidOrder        = ParseError("EOrder:Invalid order")
+	ErrServiceUnavailable  = ParseError("EService:Unavailable")
+	ErrServiceBusy         = ParseError("EService:Busy")
+	ErrMarketCancelOnly    = ParseError("EService:Market in cancel_only mode")
+	ErrMarketPostOnly      = ParseError("EService:Market in post_only mode")
+	ErrDeadlineElapsed     = ParseError("EService:Deadline elapsed")
+	ErrUnknownWithdrawKey  = ParseError("EFunding:Unknown withdraw key")
+	ErrInvalidAmount       = ParseError("EFunding:Invalid amount")
+)
+
+// Error is a single message of the error array of a response,
+// given by Kraken as <severity><category>:<message>,
+// e.g. EOrder:Insufficient funds.
+type Error struct {
+	// Severity: E (error) or W (warning).
+	Severity string
+	// Category, e.g. General, API, Query, Order, Service.
+	Category string
+	// Message, including its details if any.
+	Message string
+}
+
+// ParseError splits a message of the error array of a response.
+// A message which does not follow the <severity><category>:<message> format
+// is kept whole as an error of the General category.
+func ParseError(s string) *Error {
+	i := strings.IndexByte(s, ':')
+	if i < 2 || (s[0] != 'E' && s[0] != 'W') {
+		return &Error{Severity: SeverityError, Category: CategoryGeneral, Message: s}
+	}
+	return &Error{Severity: s[:1], Category: s[1:i], Message: s[i+1:]}
+}
+
+// Error returns the message in Kraken's format.
+func (e *Error) Error() string {
+	return e.Severity + e.Category + ":" + e.Message
+}
+
+// Is reports whether target is an *Error of the same severity and category,
+// whose message is the one of e or a prefix of it followed by details.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.Severity != e.Severity || t.Category != e.Category {
+		return false
+	}
+	return e.Message == t.Message || strings.HasPrefix(e.Message, t.Message+":")
+}
+
+// APIError represents JSON error type.
+// It holds all the messages of the error array of a response.
+type APIError []string
+
+// Error joins all the messages.
+func (e APIError) Error() string {
+	return "JSON Error: " + strings.Join(e, ", ")
+}
+
+// Errors returns the parsed messages.
+func (e APIError) Errors() []*Error {
+	errs := make([]*Error, len(e))
+	for i, s := range e {
+		errs[i] = ParseError(s)
+	}
+	return errs
+}
+
+// Is reports whether any of the messages matches target (see Error.Is).
+func (e APIError) Is(target error) bool {
+	for _, err := range e.Errors() {
+		if err.Is(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets target, if it is an **Error, to the first error message.
+func (e APIError) As(target interface{}) bool {
+	t, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+	for _, err := range e.Errors() {
+		if err.Severity == SeverityError {
+			*t = err
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns e as an error if it holds at least one error message,
+// nil if it is empty or only holds warnings.
+func (e APIError) Err() error {
+	for _, err := range e.Errors() {
+		if err.Severity == SeverityError {
+			return e
+		}
+	}
+	return nil
+}
+
+// HTTPErrorBodyLimit is the maximum length of the body snippet kept by HTTPError.
+const HTTPErrorBodyLimit = 512
+
+// HTTPError is returned when a response is not successful (non-2xx status)
+// or is not the expected JSON, e.g. the HTML page of a proxy or of a
+// maintenance.
+type HTTPError struct {
+	// StatusCode of the response, e.g. 503.
+	StatusCode int
+	// Status of the response, e.g. 503 Service Unavailable.
+	Status string
+	// Header of the response, including Retry-After if given.
+	Header http.Header
+	// Body is the beginning of the response body, truncated to
+	// HTTPErrorBodyLimit bytes.
+	Body string
+	// Err is the APIError given in the body, or the JSON decoding error,
+	// if any.
+	Err error
+}
+
+// newHTTPError returns the HTTPError of resp, whose body has been read into body.
+// If err is nil, the error messages possibly given by the body are kept.
+func newHTTPError(resp *http.Response, body []byte, err error) *HTTPError {
+	if err == nil {
+		var dat response
+		if json.Unmarshal(body, &dat) == nil {
+			err = dat.Error.Err()
+		}
+	}
+
+	if len(body) > HTTPErrorBodyLimit {
+		body = body[:HTTPErrorBodyLimit]
+	}
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       strings.ToValidUTF8(string(body), ""),
+		Err:        err,
+	}
+}
+
+// Error gives the status and the error of the body, or the body itself.
+func (e *HTTPError) Error() string {
+	msg := "HTTP Error: " + e.Status
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	if body := strings.TrimSpace(e.Body); body != "" {
+		return msg + ": " + body
+	}
+	return msg
+}
+
+// Unwrap returns the error given in the body, if any.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the delay asked by the Retry-After header, given either
+// in seconds or as an HTTP date, 0 if none.
+func (e *HTTPError) RetryAfter() time.Duration {
+	v := e.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package kraken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseError(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		expected Error
+	}{
+		{"EOrder:Insufficient funds", Error{SeverityError, CategoryOrder, "Insufficient funds"}},
+		{"EGeneral:Invalid arguments:volume", Error{SeverityError, CategoryGeneral, "Invalid arguments:volume"}},
+		{"WGeneral:Unknown field", Error{SeverityWarning, CategoryGeneral, "Unknown field"}},
+		{"Something went wrong", Error{SeverityError, CategoryGeneral, "Something went wrong"}},
+	}
+	for _, tc := range testCases {
+		e := ParseError(tc.raw)
+		if *e != tc.expected {
+			t.Errorf("%s expected: %+v, got: %+v", tc.raw, tc.expected, *e)
+		}
+	}
+	if ParseError("EOrder:Insufficient funds").Error() != "EOrder:Insufficient funds" {
+		t.Error("Error should return the message in Kraken's format")
+	}
+}
+
+func Test_APIError_Is(t *testing.T) {
+	err := error(APIError{"WGeneral:Unknown field", "EGeneral:Invalid arguments:volume", "EAPI:Invalid nonce"})
+	wrapped := fmt.Errorf("placing order: %w", err)
+
+	for _, sentinel := range []error{ErrInvalidArguments, ErrInvalidNonce} {
+		if !errors.Is(wrapped, sentinel) {
+			t.Errorf("%v should match %v", err, sentinel)
+		}
+	}
+	for _, sentinel := range []error{ErrRateLimitExceeded, ErrInsufficientFunds, ParseError("EGeneral:Invalid")} {
+		if errors.Is(wrapped, sentinel) {
+			t.Errorf("%v should not match %v", err, sentinel)
+		}
+	}
+
+	var e *Error
+	if !errors.As(wrapped, &e) || e.Category != CategoryGeneral || e.Message != "Invalid arguments:volume" {
+		t.Errorf("errors.As should give the first error message, got: %+v", e)
+	}
+	var apiErr APIError
+	if !errors.As(wrapped, &apiErr) || len(apiErr) != 3 {
+		t.Errorf("errors.As should give the APIError, got: %v", apiErr)
+	}
+}
+
+func Test_APIError_Err(t *testing.T) {
+	if (APIError{}).Err() != nil || (APIError{"WGeneral:Unknown field"}).Err() != nil {
+		t.Error("Warnings only should not be an error")
+	}
+	if (APIError{"EService:Busy"}).Err() == nil {
+		t.Error("An error message should be an error")
+	}
+}
+
+func Test_Kraken_APIError(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/Balance": `{"error":["EAPI:Rate limit exceeded"]}`,
+		"/0/public/Ticker":   `{"error":["EQuery:Unknown asset pair"]}`,
+	}, nil)
+	defer closeServer()
+
+	if _, err := k.Balance(context.Background()); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("Expected ErrRateLimitExceeded, got: %v", err)
+	}
+	if _, err := k.GetTickerInfo([]string{"XBTJPY"}); !errors.Is(err, ErrUnknownAssetPair) {
+		t.Errorf("Expected ErrUnknownAssetPair, got: %v", err)
+	}
+}
+
+func Test_Kraken_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/0/public/Time":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "<html>"+strings.Repeat("maintenance ", 100)+"</html>")
+		case "/0/public/Assets":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<html>Just a moment...</html>")
+		case "/0/public/AssetPairs":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"error":[],"result":`)
+		case "/0/public/Ticker":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"error":["EAPI:Rate limit exceeded"]}`)
+		}
+	}))
+	defer srv.Close()
+	k := &Kraken{BaseURL: srv.URL}
+	k.Init()
+
+	_, err := k.GetServerTime()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected an HTTPError, got: %v", err)
+	}
+	if httpErr.StatusCode != http.StatusServiceUnavailable || httpErr.RetryAfter() != 2*time.Minute {
+		t.Errorf("Unexpected status or Retry-After: %d, %v", httpErr.StatusCode, httpErr.RetryAfter())
+	}
+	if len(httpErr.Body) != HTTPErrorBodyLimit || !strings.HasPrefix(httpErr.Body, "<html>maintenance") {
+		t.Errorf("Body should be truncated to %d bytes, got %d: %q", HTTPErrorBodyLimit, len(httpErr.Body), httpErr.Body)
+	}
+
+	_, err = k.GetAssetsInfo()
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusOK || httpErr.Body != "<html>Just a moment...</html>" {
+		t.Errorf("Expected an HTTPError for a non-JSON response, got: %v", err)
+	}
+
+	_, err = k.GetTradablePairs()
+	if !errors.As(err, &httpErr) || httpErr.Err == nil {
+		t.Errorf("Expected an HTTPError for an invalid JSON response, got: %v", err)
+	}
+
+	_, err = k.GetTickerInfo([]string{"XXBTZEUR"})
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected an HTTPError, got: %v", err)
+	}
+	if !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("The HTTPError should match the error message of its body, got: %v", err)
+	}
+}
+
+func Test_HTTPError_RetryAfter(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tc := range testCases {
+		e := &HTTPError{Header: http.Header{}}
+		if tc.header != "" {
+			e.Header.Set("Retry-After", tc.header)
+		}
+		if d := e.RetryAfter(); d != tc.expected {
+			t.Errorf("Retry-After %q: expected %v, got %v", tc.header, tc.expected, d)
+		}
+	}
+
+	e := &HTTPError{Header: http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}}
+	if d := e.RetryAfter(); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("Retry-After date: expected about 1h, got %v", d)
+	}
+}
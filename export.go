@@ -0,0 +1,336 @@
+package kraken
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AddExport requests a data export report and returns its id.
+//
+// https://docs.kraken.com/rest/#operation/addExport
+func (k *Kraken) AddExport(ctx context.Context, request *AddExportRequest) (string, error) {
+	if request == nil || request.Report == "" || request.Description == "" {
+		return "", errors.New("JSON Error: Parameters report and description cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("report", request.Report)
+	query.Add("description", request.Description)
+	if request.Format != "" {
+		query.Add("format", request.Format)
+	}
+	if len(request.Fields) > 0 {
+		query.Add("fields", strings.Join(request.Fields, ","))
+	}
+	if !request.StartTime.IsZero() {
+		query.Add("starttm", strconv.FormatInt(request.StartTime.Unix(), 10))
+	}
+	if !request.EndTime.IsZero() {
+		query.Add("endtm", strconv.FormatInt(request.EndTime.Unix(), 10))
+	}
+
+	var dat struct {
+		ID string `json:"id"`
+	}
+	if err := k.queryPrivate(ctx, endpointAddExport, query, &dat); err != nil {
+		return "", err
+	}
+
+	return dat.ID, nil
+}
+
+// ExportStatus returns the status of the reports of the given type.
+//
+// https://docs.kraken.com/rest/#operation/exportStatus
+func (k *Kraken) ExportStatus(ctx context.Context, report string) ([]ExportReport, error) {
+	if report == "" {
+		return nil, errors.New("JSON Error: Parameter report cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("report", report)
+
+	var dat []ExportReport
+	if err := k.queryPrivate(ctx, endpointExportStatus, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// RetrieveExport downloads the zip archive of a processed report.
+//
+// https://docs.kraken.com/rest/#operation/retrieveExport
+func (k *Kraken) RetrieveExport(ctx context.Context, id string) ([]byte, error) {
+	if id == "" {
+		return nil, errors.New("JSON Error: Parameter id cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("id", id)
+
+	var data []byte
+	err := k.queryPrivateWith(ctx, endpointRetrieveExport, query, func(resp *http.Response) error {
+		// errors are still reported as JSON, the archive being sent as
+		// application/zip
+		if contentType := resp.Header.Get("Content-Type"); contentType != "" && isJSON(contentType) {
+			if err := k.decodeResult(nil)(resp); err != nil {
+				return err
+			}
+			return errors.New("JSON Error: Export archive not present")
+		}
+		var err error
+		data, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// RemoveExport cancels (removeType cancel) a report being prepared or deletes
+// (removeType delete) a processed report.
+//
+// https://docs.kraken.com/rest/#operation/removeExport
+func (k *Kraken) RemoveExport(ctx context.Context, id, removeType string) (*RemoveExportInfo, error) {
+	if id == "" || removeType == "" {
+		return nil, errors.New("JSON Error: Parameters id and type cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("id", id)
+	query.Add("type", removeType)
+
+	var dat RemoveExportInfo
+	if err := k.queryPrivate(ctx, endpointRemoveExport, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// WaitExport polls the status of the report every interval
+// (DefaultExportPollInterval if zero) until it is processed.
+func (k *Kraken) WaitExport(ctx context.Context, report, id string, interval time.Duration) (*ExportReport, error) {
+	if interval <= 0 {
+		interval = DefaultExportPollInterval
+	}
+	for {
+		reports, err := k.ExportStatus(ctx, report)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, r := range reports {
+			if r.ID != id {
+				continue
+			}
+			if r.Status == ExportStatusProcessed {
+				return &r, nil
+			}
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("JSON Error: Unknown %s export %s", report, id)
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ExportLedgers requests a ledgers report, waits for it to be processed
+// (polling every interval), downloads it and deletes it from the server.
+// The ledger entries are returned in the order of the report.
+func (k *Kraken) ExportLedgers(ctx context.Context, request *AddExportRequest, interval time.Duration) ([]LedgerEntry, error) {
+	data, err := k.export(ctx, ExportLedgers, request, interval)
+	if err != nil {
+		return nil, err
+	}
+	return ReadLedgersExport(data)
+}
+
+// ExportTrades requests a trades report, waits for it to be processed
+// (polling every interval), downloads it and deletes it from the server.
+// The trades are returned in the order of the report.
+func (k *Kraken) ExportTrades(ctx context.Context, request *AddExportRequest, interval time.Duration) ([]TradeInfo, error) {
+	data, err := k.export(ctx, ExportTrades, request, interval)
+	if err != nil {
+		return nil, err
+	}
+	return ReadTradesExport(data)
+}
+
+// export runs the lifecycle of a report of the given type and returns its archive.
+// The report is removed from the server even if a step fails.
+func (k *Kraken) export(ctx context.Context, report string, request *AddExportRequest, interval time.Duration) ([]byte, error) {
+	if request == nil {
+		return nil, errors.New("JSON Error: Parameter request cannot be empty")
+	}
+	r := *request
+	r.Report = report
+
+	id, err := k.AddExport(ctx, &r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.WaitExport(ctx, report, id, interval); err != nil {
+		k.removeExport(report, id, ExportRemoveCancel)
+		return nil, err
+	}
+	data, err := k.RetrieveExport(ctx, id)
+	k.removeExport(report, id, ExportRemoveDelete)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// removeExport removes the report left by export, logging the failures.
+// It has its own timeout, ctx of export being possibly done.
+func (k *Kraken) removeExport(report, id, removeType string) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportRemoveTimeout)
+	defer cancel()
+	if _, err := k.RemoveExport(ctx, id, removeType); err != nil {
+		k.logf("kraken: could not %s %s export %s: %v", removeType, report, id, err)
+	}
+}
+
+// ReadLedgersExport parses the ledger entries of the zip archive of a ledgers report.
+func ReadLedgersExport(data []byte) ([]LedgerEntry, error) {
+	rows, err := readExport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LedgerEntry, 0, len(rows))
+	for _, row := range rows {
+		t, err := parseExportTime(row["time"])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, LedgerEntry{
+			ID:      row["txid"],
+			RefID:   row["refid"],
+			Time:    t,
+			Type:    row["type"],
+			Subtype: row["subtype"],
+			Aclass:  row["aclass"],
+			Asset:   row["asset"],
+			Amount:  row["amount"],
+			Fee:     row["fee"],
+			Balance: row["balance"],
+		})
+	}
+	return entries, nil
+}
+
+// ReadTradesExport parses the trades of the zip archive of a trades report.
+func ReadTradesExport(data []byte) ([]TradeInfo, error) {
+	rows, err := readExport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	trades := make([]TradeInfo, 0, len(rows))
+	for _, row := range rows {
+		t, err := parseExportTime(row["time"])
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, TradeInfo{
+			TxID:         row["txid"],
+			OrderTxID:    row["ordertxid"],
+			PosTxID:      row["postxid"],
+			Pair:         row["pair"],
+			Time:         t,
+			Type:         row["type"],
+			OrderType:    row["ordertype"],
+			Price:        row["price"],
+			Cost:         row["cost"],
+			Fee:          row["fee"],
+			Volume:       row["vol"],
+			Margin:       row["margin"],
+			Misc:         row["misc"],
+			PosStatus:    row["posstatus"],
+			ClosedPrice:  row["cprice"],
+			ClosedCost:   row["ccost"],
+			ClosedFee:    row["cfee"],
+			ClosedVolume: row["cvol"],
+			ClosedMargin: row["cmargin"],
+			Net:          row["net"],
+		})
+	}
+	return trades, nil
+}
+
+// readExport unzips the report and returns its rows keyed by the header
+// columns. The report is read as TSV if its file has the .tsv extension,
+// as CSV otherwise.
+func readExport(data []byte) ([]map[string]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(archive.File) == 0 {
+		return nil, errors.New("JSON Error: Export archive is empty")
+	}
+	file := archive.File[0]
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if strings.EqualFold(path.Ext(file.Name), ".tsv") {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseExportTime parses the times of the reports, either
+// "2006-01-02 15:04:05" with optional fractional seconds, in UTC,
+// or fractional unix seconds.
+func parseExportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02 15:04:05", s); err == nil {
+		return t, nil
+	}
+	return parseTimestamp(json.Number(s))
+}
@@ -0,0 +1,189 @@
+package kraken
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testExportArchive returns a zip archive holding a single file.
+func testExportArchive(t *testing.T, name, content string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(f, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const testLedgersCSV = `"txid","refid","time","type","subtype","aclass","asset","amount","fee","balance"
+"LSJ5YV-2WNUV-ZRHJFU","QCC3V7R-NMVMPB-AEZJS7","2021-03-24 17:41:56.3942","deposit","","currency","ZEUR",500.0000,0.0000,500.0000
+"L4UESK-KG3EQ-UFO4T5","TJKLXX-PGMUI-4NTLXU","2021-03-25 09:12:01","trade","","currency","XXBT",0.0100000000,0.0000000000,0.0100000000
+`
+
+func Test_ReadLedgersExport(t *testing.T) {
+	entries, err := ReadLedgersExport(testExportArchive(t, "ledgers.csv", testLedgersCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 ledger entries, got: %d", len(entries))
+	}
+	l := entries[0]
+	if l.ID != "LSJ5YV-2WNUV-ZRHJFU" || l.Type != LedgerTypeDeposit || l.Asset != "ZEUR" || l.Balance != "500.0000" {
+		t.Errorf("Unexpected ledger entry: %+v", l)
+	}
+	expected := time.Date(2021, 3, 24, 17, 41, 56, 394200000, time.UTC)
+	if !l.Time.Equal(expected) {
+		t.Errorf("Ledger time expected: %v, got: %v", expected, l.Time)
+	}
+}
+
+func Test_ReadTradesExport(t *testing.T) {
+	const tsv = "txid\tordertxid\tpair\ttime\ttype\tordertype\tprice\tcost\tfee\tvol\tmargin\tmisc\tledgers\n" +
+		"THVRQM-33VKH-UCI7BS\tOQCLML-BW3P3-BUCMWZ\tXXBTZUSD\t1616667796.8802\tbuy\tlimit\t30010.00000\t600.20000\t0.00000\t0.02000000\t0.00000\t\tL4UESK-KG3EQ-UFO4T5\n"
+	trades, err := ReadTradesExport(testExportArchive(t, "trades.tsv", tsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("Expected 1 trade, got: %d", len(trades))
+	}
+	tr := trades[0]
+	if tr.TxID != "THVRQM-33VKH-UCI7BS" || tr.Pair != XXBTZUSD || tr.Volume != "0.02000000" || tr.Cost != "600.20000" {
+		t.Errorf("Unexpected trade: %+v", tr)
+	}
+	if !tr.Time.Equal(time.Unix(1616667796, 880200000)) {
+		t.Errorf("Unexpected trade time: %v", tr.Time)
+	}
+}
+
+func Test_Kraken_ExportLedgers(t *testing.T) {
+	const statusTemplate = `{"error":[],"result":[{"id":"OTHER","report":"ledgers","status":"Queued"},
+	{"id":"TCJA","descr":"monthly","format":"CSV","report":"ledgers","status":"%s",
+	"createdtm":"1616669085","expiretm":"1617878685","starttm":"1616669093","completedtm":"%s",
+	"datastarttm":"1614556800","dataendtm":"1616669085","aclass":"forex","asset":"all"}]}`
+	var statusCalls int
+	var removed string
+	routes := map[string]string{
+		"/0/private/AddExport":      `{"error":[],"result":{"id":"TCJA"}}`,
+		"/0/private/ExportStatus":   "",
+		"/0/private/RetrieveExport": string(testExportArchive(t, "ledgers.csv", testLedgersCSV)),
+		"/0/private/RemoveExport":   `{"error":[],"result":{"delete":true}}`,
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		switch path {
+		case "/0/private/AddExport":
+			if form.Get("report") != ExportLedgers || form.Get("description") != "monthly" || form.Get("starttm") != "1614556800" {
+				t.Errorf("Unexpected export parameters: %v", form)
+			}
+		case "/0/private/ExportStatus":
+			statusCalls++
+			if statusCalls == 1 {
+				routes[path] = fmt.Sprintf(statusTemplate, ExportStatusProcessing, "0")
+			} else {
+				routes[path] = fmt.Sprintf(statusTemplate, ExportStatusProcessed, "1616669093")
+			}
+		case "/0/private/RetrieveExport":
+			if form.Get("id") != "TCJA" {
+				t.Errorf("id expected: TCJA, got: %s", form.Get("id"))
+			}
+		case "/0/private/RemoveExport":
+			removed = form.Get("id") + " " + form.Get("type")
+		}
+	})
+	defer closeServer()
+
+	request := &AddExportRequest{Description: "monthly", StartTime: time.Unix(1614556800, 0)}
+	entries, err := k.ExportLedgers(context.Background(), request, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].ID != "L4UESK-KG3EQ-UFO4T5" {
+		t.Errorf("Unexpected exported ledger entries: %+v", entries)
+	}
+	if statusCalls != 2 {
+		t.Errorf("The status should be polled until processed, got %d calls", statusCalls)
+	}
+	if removed != "TCJA delete" {
+		t.Errorf("The report should be deleted once downloaded, got: %s", removed)
+	}
+
+	reports, err := k.ExportStatus(context.Background(), ExportLedgers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reports[1].CompletedTime.Equal(time.Unix(1616669093, 0)) || !reports[0].CreatedTime.IsZero() {
+		t.Errorf("Unexpected report times: %+v", reports)
+	}
+}
+
+func Test_Kraken_ExportLedgers_Failed(t *testing.T) {
+	const statusTemplate = `{"error":[],"result":[{"id":"TCJA","report":"ledgers","status":"%s"}]}`
+	var removed string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	routes := map[string]string{
+		"/0/private/AddExport":      `{"error":[],"result":{"id":"TCJA"}}`,
+		"/0/private/ExportStatus":   fmt.Sprintf(statusTemplate, ExportStatusQueued),
+		"/0/private/RetrieveExport": `{"error":["EQuery:Unknown export"]}`,
+		"/0/private/RemoveExport":   `{"error":[],"result":{"cancel":true}}`,
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		switch path {
+		case "/0/private/ExportStatus":
+			cancel()
+		case "/0/private/RemoveExport":
+			removed = form.Get("id") + " " + form.Get("type")
+		}
+	})
+	defer closeServer()
+
+	// a report still queued when ctx is done is cancelled
+	if _, err := k.ExportLedgers(ctx, &AddExportRequest{Description: "monthly"}, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if removed != "TCJA cancel" {
+		t.Errorf("The queued report should be cancelled, got: %q", removed)
+	}
+
+	// a processed report which cannot be downloaded is deleted
+	removed = ""
+	routes["/0/private/ExportStatus"] = fmt.Sprintf(statusTemplate, ExportStatusProcessed)
+	if _, err := k.ExportLedgers(context.Background(), &AddExportRequest{Description: "monthly"}, time.Millisecond); err == nil {
+		t.Error("Expected an error")
+	}
+	if removed != "TCJA delete" {
+		t.Errorf("The processed report should be deleted, got: %q", removed)
+	}
+}
+
+func Test_Kraken_RetrieveExport_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "Application/JSON; charset=UTF-8")
+		io.WriteString(w, `{"error":["EQuery:Unknown export"]}`)
+	}))
+	defer srv.Close()
+	k := NewClient(WithBaseURL(srv.URL), WithCredentials(testAPIKey, testAPISecret))
+
+	data, err := k.RetrieveExport(context.Background(), "TCJA")
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || data != nil {
+		t.Errorf("Expected the APIError of the JSON response, got: %v, %d bytes", err, len(data))
+	}
+}
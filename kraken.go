@@ -1,13 +1,381 @@
 package kraken
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the HTTP client timeout used by NewClient.
+	DefaultTimeout = 30 * time.Second
+	// DefaultUserAgent is the User-Agent header sent by clients created with NewClient.
+	DefaultUserAgent = "kraken_api-go"
+)
+
+// Logger is the minimal logging interface used by the client.
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// RateLimiter paces the calls made by the client.
+type RateLimiter interface {
+	// Wait blocks until a call to the given endpoint may be made,
+	// or returns an error if ctx is done first.
+	Wait(ctx context.Context, endpoint string) error
+}
+
+// RetryPolicy decides whether a failed call is attempted again.
+// The calls placing orders, moving funds or creating reports are not retried,
+// except AddOrder after looking up the order by its UserRef.
+type RetryPolicy interface {
+	// Backoff is called after the attempt-th failed attempt (starting at 1),
+	// elapsed time after the first attempt started. It returns the delay
+	// before the next attempt, or false if err should be returned to the caller.
+	Backoff(attempt int, elapsed time.Duration, err error) (time.Duration, bool)
+}
 
 // Kraken main client for the API.
 type Kraken struct {
 	Client *http.Client
+	// BaseURL is the scheme and host the requests are sent to,
+	// e.g. https://api.kraken.com or the address of a local stand-in.
+	BaseURL string
+	// APIVersion is the version path segment placed after the BaseURL.
+	APIVersion string
+	// UserAgent is sent with every request when not empty.
+	UserAgent string
+	// Logger receives request and retry traces when not nil.
+	Logger Logger
+	// Key is the API key used for the private endpoints.
+	Key string
+	// Secret is the base64 encoded private key used for the private endpoints.
+	Secret string
+	// RateLimiter paces the calls when not nil.
+	RateLimiter RateLimiter
+	// RetryPolicy retries the failed calls when not nil.
+	RetryPolicy RetryPolicy
+
+	nonce   nonceGenerator
+	pairs   pairCache
+	wsToken wsTokenCache
+}
+
+// Option configures a client created with NewClient.
+type Option func(*Kraken)
+
+// NewClient creates a client with the default settings
+// (see DefaultBaseURL, DefaultAPIVersion, DefaultTimeout and DefaultUserAgent),
+// modified by the given options in order.
+func NewClient(opts ...Option) *Kraken {
+	k := &Kraken{
+		Client:     &http.Client{Timeout: DefaultTimeout},
+		BaseURL:    DefaultBaseURL,
+		APIVersion: DefaultAPIVersion,
+		UserAgent:  DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// WithHTTPClient makes the client send its requests through c.
+func WithHTTPClient(c *http.Client) Option {
+	return func(k *Kraken) {
+		k.Client = c
+	}
+}
+
+// WithTransport sets the transport of the HTTP client.
+// The HTTP client is copied, so one passed to WithHTTPClient is left untouched.
+func WithTransport(t http.RoundTripper) Option {
+	return func(k *Kraken) {
+		c := *k.httpClient()
+		c.Transport = t
+		k.Client = &c
+	}
+}
+
+// WithTimeout sets the timeout of the HTTP client, zero meaning no timeout.
+// The HTTP client is copied, so one passed to WithHTTPClient is left untouched.
+func WithTimeout(d time.Duration) Option {
+	return func(k *Kraken) {
+		c := *k.httpClient()
+		c.Timeout = d
+		k.Client = &c
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(k *Kraken) {
+		k.UserAgent = ua
+	}
+}
+
+// WithBaseURL points the client at another host, e.g. a staging gateway,
+// a recording proxy or an httptest.Server.
+func WithBaseURL(baseURL string) Option {
+	return func(k *Kraken) {
+		k.BaseURL = baseURL
+	}
+}
+
+// WithAPIVersion sets the version path segment of the endpoint URLs.
+func WithAPIVersion(version string) Option {
+	return func(k *Kraken) {
+		k.APIVersion = version
+	}
+}
+
+// WithLogger sets the logger receiving request and retry traces.
+func WithLogger(l Logger) Option {
+	return func(k *Kraken) {
+		k.Logger = l
+	}
+}
+
+// WithCredentials sets the API key and the base64 encoded secret
+// used for the private endpoints.
+func WithCredentials(key, secret string) Option {
+	return func(k *Kraken) {
+		k.Key = key
+		k.Secret = secret
+	}
+}
+
+// WithRateLimiter sets the limiter pacing the calls.
+func WithRateLimiter(l RateLimiter) Option {
+	return func(k *Kraken) {
+		k.RateLimiter = l
+	}
+}
+
+// WithRetryPolicy sets the policy retrying the failed calls.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(k *Kraken) {
+		k.RetryPolicy = p
+	}
 }
 
 // Init initialize the client instance.
+// BaseURL and APIVersion are set to their defaults unless already set.
 func (k *Kraken) Init() {
 	k.Client = &http.Client{}
+	if k.BaseURL == "" {
+		k.BaseURL = DefaultBaseURL
+	}
+	if k.APIVersion == "" {
+		k.APIVersion = DefaultAPIVersion
+	}
+}
+
+// publicURL returns the full URL of the given public endpoint.
+func (k *Kraken) publicURL(endpoint string) string {
+	return k.endpointURL("public", endpoint)
+}
+
+// endpointURL builds <base url>/<api version>/<access>/<endpoint>,
+// falling back to the default for an unset BaseURL.
+func (k *Kraken) endpointURL(access, endpoint string) string {
+	base := k.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return strings.TrimRight(base, "/") + k.endpointPath(access, endpoint)
+}
+
+// endpointPath builds /<api version>/<access>/<endpoint>,
+// falling back to the default for an unset APIVersion.
+func (k *Kraken) endpointPath(access, endpoint string) string {
+	version := k.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+	return "/" + version + "/" + access + "/" + endpoint
+}
+
+// httpClient returns the HTTP client, http.DefaultClient if none was set.
+func (k *Kraken) httpClient() *http.Client {
+	if k.Client == nil {
+		return http.DefaultClient
+	}
+	return k.Client
+}
+
+// logf writes to the Logger if there is one.
+func (k *Kraken) logf(format string, v ...interface{}) {
+	if k.Logger != nil {
+		k.Logger.Printf(format, v...)
+	}
+}
+
+// response is the envelope common to all the API responses.
+type response struct {
+	Error  APIError        `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// queryPublic calls the given public endpoint with the query values
+// and decodes the result into result.
+func (k *Kraken) queryPublic(ctx context.Context, endpoint string, values url.Values, result interface{}) error {
+	return k.query(ctx, endpoint, true, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", k.publicURL(endpoint), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.URL.RawQuery = values.Encode()
+		return req, nil
+	}, k.decodeResult(result))
+}
+
+// nonIdempotent holds the endpoints whose calls are never retried by the
+// RetryPolicy, since a failed call may still have been carried out.
+// Every endpoint placing orders, moving funds or creating reports is to be
+// added. AddOrder retries after looking up the order (see AddOrder).
+var nonIdempotent = map[string]bool{
+	endpointAddOrder:  true,
+	endpointWithdraw:  true,
+	endpointStake:     true,
+	endpointUnstake:   true,
+	endpointAddExport: true,
+	endpointEditOrder: true,
+	endpointAddBatch:  true,
+}
+
+// isIdempotent reports whether the call to endpoint with the given values may
+// be retried.
+func isIdempotent(endpoint string, values url.Values) bool {
+	if nonIdempotent[endpoint] {
+		return false
+	}
+	// a new deposit address is generated on every call
+	return endpoint != endpointDepositAddresses || values.Get("new") != "true"
+}
+
+// query performs the request built by newRequest, retrying it as long as the
+// RetryPolicy allows if the call is idempotent, and handles the response with
+// decode. newRequest is called for every attempt.
+func (k *Kraken) query(ctx context.Context, endpoint string, idempotent bool, newRequest func() (*http.Request, error), decode func(*http.Response) error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := k.attempt(ctx, endpoint, newRequest, decode)
+		if err == nil || k.RetryPolicy == nil || !idempotent || ctx.Err() != nil {
+			return err
+		}
+		delay, retry := k.RetryPolicy.Backoff(attempt, time.Since(start), err)
+		if !retry {
+			return err
+		}
+		k.logf("kraken: %s attempt %d failed, retrying in %v: %v", endpoint, attempt, delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// attempt performs a single request and decodes its response.
+func (k *Kraken) attempt(ctx context.Context, endpoint string, newRequest func() (*http.Request, error), decode func(*http.Response) error) error {
+	if k.RateLimiter != nil {
+		if err := k.RateLimiter.Wait(ctx, endpoint); err != nil {
+			return err
+		}
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if k.UserAgent != "" {
+		req.Header.Set("User-Agent", k.UserAgent)
+	}
+	k.logf("kraken: %s %s", req.Method, req.URL)
+
+	resp, err := k.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		err = newHTTPError(resp, body, nil)
+	} else {
+		err = decode(resp)
+	}
+
+	if l, ok := k.RateLimiter.(interface{ Exceeded() }); ok && errors.Is(err, ErrRateLimitExceeded) {
+		l.Exceeded()
+	}
+	return err
+}
+
+// maxErrorBody is the maximum length of the body of a non-2xx response read
+// to find its error messages.
+const maxErrorBody = 64 << 10
+
+// isJSON reports whether the Content-Type header contentType is JSON,
+// missing being accepted.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeResult returns the decoder of the JSON responses, which checks their
+// error messages (logging the warnings) and unmarshals their result into
+// result (if not nil).
+func (k *Kraken) decodeResult(result interface{}) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isJSON(resp.Header.Get("Content-Type")) {
+			return newHTTPError(resp, body, nil)
+		}
+		var dat response
+		if err := json.Unmarshal(body, &dat); err != nil {
+			return newHTTPError(resp, body, err)
+		}
+
+		if err := dat.Error.Err(); err != nil {
+			return err
+		}
+		if len(dat.Error) > 0 {
+			k.logf("kraken: %s warnings: %v", resp.Request.URL.Path, []string(dat.Error))
+		}
+
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(dat.Result, result)
+	}
+}
+
+// sleepContext waits for d to pass or ctx to be done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
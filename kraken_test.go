@@ -0,0 +1,233 @@
+package kraken
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRoutesMu guards the routes of the servers started by newTestServer,
+// which may still be serving a request when a test updates them.
+var testRoutesMu sync.Mutex
+
+// setTestRoute serves body at path from the server started with routes.
+func setTestRoute(routes map[string]string, path, body string) {
+	testRoutesMu.Lock()
+	defer testRoutesMu.Unlock()
+	routes[path] = body
+}
+
+// newTestServer starts a local stand-in for the Kraken API which serves the
+// given JSON bodies keyed by request path, and returns a client pointed at it
+// together with the function shutting the server down. The routes are to be
+// updated with setTestRoute.
+func newTestServer(routes map[string]string) (*Kraken, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testRoutesMu.Lock()
+		body, ok := routes[r.URL.Path]
+		testRoutesMu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+
+	k := &Kraken{BaseURL: srv.URL}
+	k.Init()
+	return k, srv.Close
+}
+
+func Test_Kraken_Init(t *testing.T) {
+	var k Kraken
+	k.Init()
+
+	if k.Client == nil {
+		t.Error("Client should be set")
+	}
+	if k.BaseURL != DefaultBaseURL {
+		t.Errorf("BaseURL expected: %s, got: %s", DefaultBaseURL, k.BaseURL)
+	}
+	if k.APIVersion != DefaultAPIVersion {
+		t.Errorf("APIVersion expected: %s, got: %s", DefaultAPIVersion, k.APIVersion)
+	}
+}
+
+func Test_Kraken_endpointURL(t *testing.T) {
+	testCases := []struct {
+		baseURL, apiVersion string
+		expected            string
+	}{
+		{"", "", "https://api.kraken.com/0/public/Time"},
+		{"http://127.0.0.1:8080/", "", "http://127.0.0.1:8080/0/public/Time"},
+		{"http://proxy/kraken", "1", "http://proxy/kraken/1/public/Time"},
+	}
+
+	for _, tc := range testCases {
+		k := Kraken{BaseURL: tc.baseURL, APIVersion: tc.apiVersion}
+		if got := k.publicURL(endpointGetServerTime); got != tc.expected {
+			t.Errorf("publicURL expected: %s, got: %s", tc.expected, got)
+		}
+	}
+}
+
+func Test_NewClient(t *testing.T) {
+	k := NewClient()
+	if k.Client == nil || k.Client.Timeout != DefaultTimeout {
+		t.Errorf("Client should be set with the %v timeout", DefaultTimeout)
+	}
+	if k.BaseURL != DefaultBaseURL || k.APIVersion != DefaultAPIVersion {
+		t.Errorf("Unexpected endpoint defaults: %s, %s", k.BaseURL, k.APIVersion)
+	}
+	if k.UserAgent != DefaultUserAgent {
+		t.Errorf("UserAgent expected: %s, got: %s", DefaultUserAgent, k.UserAgent)
+	}
+
+	shared := &http.Client{}
+	k = NewClient(
+		WithHTTPClient(shared),
+		WithTimeout(5*time.Second),
+		WithBaseURL("http://127.0.0.1:8080"),
+		WithAPIVersion("1"),
+		WithCredentials("key", "c2VjcmV0"),
+	)
+	if k.Client.Timeout != 5*time.Second {
+		t.Errorf("Client timeout expected: 5s, got: %v", k.Client.Timeout)
+	}
+	if shared.Timeout != 0 {
+		t.Error("WithTimeout should not modify the client passed to WithHTTPClient")
+	}
+	if k.BaseURL != "http://127.0.0.1:8080" || k.APIVersion != "1" {
+		t.Errorf("Unexpected endpoint settings: %s, %s", k.BaseURL, k.APIVersion)
+	}
+	if k.Key != "key" || k.Secret != "c2VjcmV0" {
+		t.Error("Credentials are not set")
+	}
+}
+
+type testLimiter struct {
+	endpoints []string
+}
+
+func (l *testLimiter) Wait(ctx context.Context, endpoint string) error {
+	l.endpoints = append(l.endpoints, endpoint)
+	return nil
+}
+
+type testRetryPolicy struct {
+	attempts int
+}
+
+func (p *testRetryPolicy) Backoff(attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	p.attempts = attempt
+	return time.Millisecond, attempt < 3
+}
+
+func Test_Kraken_query(t *testing.T) {
+	var calls int
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		if calls < 3 {
+			io.WriteString(w, `{"error":["EService:Unavailable"]}`)
+			return
+		}
+		io.WriteString(w, `{"error":[],"result":{"unixtime":1493926890,"rfc1123":""}}`)
+	}))
+	defer srv.Close()
+
+	limiter := &testLimiter{}
+	policy := &testRetryPolicy{}
+	k := NewClient(WithBaseURL(srv.URL), WithUserAgent("test-agent"),
+		WithRateLimiter(limiter), WithRetryPolicy(policy))
+
+	r, err := k.GetServerTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Unixtime != 1493926890 {
+		t.Errorf("Unixtime expected: 1493926890, got: %d", r.Unixtime)
+	}
+	if calls != 3 || policy.attempts != 2 {
+		t.Errorf("Expected 3 calls and 2 retries, got: %d, %d", calls, policy.attempts)
+	}
+	if len(limiter.endpoints) != 3 || limiter.endpoints[0] != endpointGetServerTime {
+		t.Errorf("Every attempt should wait on the limiter, got: %v", limiter.endpoints)
+	}
+	if userAgent != "test-agent" {
+		t.Errorf("User-Agent expected: test-agent, got: %s", userAgent)
+	}
+
+	calls = 0
+	policy.attempts = 0
+	k.RetryPolicy = nil
+	if _, err := k.GetServerTime(); err == nil || calls != 1 {
+		t.Errorf("Without a retry policy the error should be returned after 1 call, got: %v, %d", err, calls)
+	}
+
+	calls = 0
+	k.RetryPolicy = policy
+	err = k.query(context.Background(), endpointGetServerTime, false, func() (*http.Request, error) {
+		return http.NewRequest("GET", k.publicURL(endpointGetServerTime), nil)
+	}, k.decodeResult(nil))
+	if err == nil || calls != 1 {
+		t.Errorf("A non-idempotent call should not be retried, got: %v, %d", err, calls)
+	}
+}
+
+func Test_Kraken_query_NonIdempotent(t *testing.T) {
+	calls := map[string]int{}
+	routes := map[string]string{}
+	for endpoint := range nonIdempotent {
+		routes["/0/private/"+endpoint] = `{"error":["EService:Unavailable"]}`
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		calls[path]++
+	})
+	defer closeServer()
+	k.RetryPolicy = &testRetryPolicy{}
+
+	for endpoint := range nonIdempotent {
+		if err := k.queryPrivate(context.Background(), endpoint, nil, nil); err == nil {
+			t.Errorf("%s: expected an error", endpoint)
+		}
+		if n := calls["/0/private/"+endpoint]; n != 1 {
+			t.Errorf("%s should not be retried, got %d calls", endpoint, n)
+		}
+	}
+}
+
+func Test_Kraken_DepositAddresses_NewNotRetried(t *testing.T) {
+	var calls int
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/DepositAddresses": `{"error":["EService:Unavailable"]}`,
+	}, func(path string, form url.Values) {
+		calls++
+	})
+	defer closeServer()
+	k.RetryPolicy = &testRetryPolicy{}
+
+	if _, err := k.DepositAddresses(context.Background(), "XBT", "Bitcoin", true); err == nil || calls != 1 {
+		t.Errorf("Generating a new address should not be retried, got: %v, %d calls", err, calls)
+	}
+	calls = 0
+	if _, err := k.DepositAddresses(context.Background(), "XBT", "Bitcoin", false); err == nil || calls != 3 {
+		t.Errorf("Listing the addresses should be retried, got: %v, %d calls", err, calls)
+	}
+}
+
+func Test_sleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
@@ -1 +1,1317 @@
 package kraken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nonceGenerator issues strictly increasing nonces for the private calls.
+// The nonces are based on the current time in microseconds, so they also
+// keep increasing across restarts of the process.
+type nonceGenerator struct {
+	mu   sync.Mutex
+	last int64
+}
+
+// next returns a nonce greater than all the previously returned ones.
+func (g *nonceGenerator) next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := time.Now().UnixNano() / int64(time.Microsecond)
+	if n <= g.last {
+		n = g.last + 1
+	}
+	g.last = n
+	return n
+}
+
+// signature computes the API-Sign header of a private request: the
+// HMAC-SHA512 of the URI path followed by SHA256(nonce + POST data), keyed
+// by the decoded API secret, encoded in base64.
+//
+// https://docs.kraken.com/rest/#section/Authentication/Headers-and-Signature
+func signature(secret []byte, path, nonce, postData string) string {
+	sha := sha256.Sum256([]byte(nonce + postData))
+
+	mac := hmac.New(sha512.New, secret)
+	mac.Write([]byte(path))
+	mac.Write(sha[:])
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// privateURL returns the full URL of the given private endpoint.
+func (k *Kraken) privateURL(endpoint string) string {
+	return k.endpointURL("private", endpoint)
+}
+
+// queryPrivate calls the given private endpoint with the form values,
+// signed with the client's credentials, and decodes the result into result.
+// Every attempt is sent with a fresh nonce.
+func (k *Kraken) queryPrivate(ctx context.Context, endpoint string, values url.Values, result interface{}) error {
+	return k.queryPrivateWith(ctx, endpoint, values, k.decodeResult(result))
+}
+
+// queryPrivateWith is queryPrivate handling the response with decode,
+// for the endpoints which do not answer with JSON.
+func (k *Kraken) queryPrivateWith(ctx context.Context, endpoint string, values url.Values, decode func(*http.Response) error) error {
+	if k.Key == "" || k.Secret == "" {
+		return errors.New("Private API Error: Key and Secret are required")
+	}
+	secret, err := base64.StdEncoding.DecodeString(k.Secret)
+	if err != nil {
+		return errors.New("Private API Error: Secret is not valid base64: " + err.Error())
+	}
+	path := k.endpointPath("private", endpoint)
+
+	return k.query(ctx, endpoint, isIdempotent(endpoint, values), func() (*http.Request, error) {
+		form := url.Values{}
+		for key, v := range values {
+			form[key] = v
+		}
+		nonce := strconv.FormatInt(k.nonce.next(), 10)
+		form.Set("nonce", nonce)
+		postData := form.Encode()
+
+		req, err := http.NewRequest("POST", k.privateURL(endpoint), strings.NewReader(postData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("API-Key", k.Key)
+		req.Header.Set("API-Sign", signature(secret, path, nonce, postData))
+		return req, nil
+	}, decode)
+}
+
+// Balance returns the account balance of every asset, keyed by the asset ids
+// used in AssetsInfoMap.
+//
+// https://www.kraken.com/help/api#get-account-balance
+func (k *Kraken) Balance(ctx context.Context) (*BalanceMap, error) {
+	var dat BalanceMap
+	if err := k.queryPrivate(ctx, endpointBalance, nil, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// TradeBalance returns the trade balance of the account.
+//
+// Input (options may be nil):
+//
+//	aclass = asset class (optional): currency (default)
+//	asset = base asset used to determine balance (optional): ZUSD (default)
+//
+// https://www.kraken.com/help/api#get-trade-balance
+func (k *Kraken) TradeBalance(ctx context.Context, options *TradeBalanceOptions) (*TradeBalanceInfo, error) {
+	query := url.Values{}
+	if options != nil {
+		if options.AssetClass != "" {
+			query.Add("aclass", options.AssetClass)
+		}
+		if options.Asset != "" {
+			query.Add("asset", options.Asset)
+		}
+	}
+
+	var dat TradeBalanceInfo
+	if err := k.queryPrivate(ctx, endpointTradeBalance, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// PairCacheTTL is the time the tradable pairs used for validating the orders
+// are kept before being fetched again.
+var PairCacheTTL = time.Hour
+
+// pairCacheMinAge is the age from which the tradable pairs are fetched again
+// when a pair is not found, for the newly listed ones.
+const pairCacheMinAge = time.Minute
+
+// pairCache keeps the tradable pairs, fetched on first use and then every
+// PairCacheTTL, for validating the orders.
+type pairCache struct {
+	mu      sync.Mutex
+	pairs   AssetPairMap
+	updated time.Time
+}
+
+// get returns the cached pairs and their age.
+func (c *pairCache) get() (AssetPairMap, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pairs, time.Since(c.updated)
+}
+
+// set caches the pairs.
+func (c *pairCache) set(pairs AssetPairMap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pairs = pairs
+	c.updated = time.Now()
+}
+
+// assetPair returns the info of the pair given by its key or altname.
+func (k *Kraken) assetPair(ctx context.Context, pair string) (*AssetPairInfo, error) {
+	pairs, age := k.pairs.get()
+	fetched := false
+	if pairs == nil || age > PairCacheTTL {
+		fresh, err := k.GetTradablePairsCtx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pairs, fetched = *fresh, true
+		k.pairs.set(pairs)
+	}
+
+	info, ok := findPair(pairs, pair)
+	if !ok && !fetched && age > pairCacheMinAge {
+		fresh, err := k.GetTradablePairsCtx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		k.pairs.set(*fresh)
+		info, ok = findPair(*fresh, pair)
+	}
+	if !ok {
+		return nil, ParseError("EQuery:Unknown asset pair:" + pair)
+	}
+	return &info, nil
+}
+
+// findPair returns the info of the pair given by its key or altname.
+func findPair(pairs AssetPairMap, pair string) (AssetPairInfo, bool) {
+	if info, ok := pairs[pair]; ok {
+		return info, true
+	}
+	for _, info := range pairs {
+		if info.Altname == pair {
+			return info, true
+		}
+	}
+	return AssetPairInfo{}, false
+}
+
+// AddOrder places a new order, after validating it against the pair info
+// (see AddOrderRequest.Validate). With order.ValidateOnly set the order is only
+// validated by the server and not submitted.
+//
+// A failed placement is never retried blindly: if the RetryPolicy allows
+// a retry and order.UserRef is set, the orders opened with that UserRef are
+// looked up first, and returned if found instead of placing the order again.
+// The UserRef should thus be unique to the order. Without UserRef the order
+// is not retried.
+//
+// Result:
+//
+//	descr = order description info
+//	txid = array of transaction ids for order (if order was added successfully)
+//
+// https://www.kraken.com/help/api#add-standard-order
+func (k *Kraken) AddOrder(ctx context.Context, order *AddOrderRequest) (*AddOrderInfo, error) {
+	if order == nil {
+		return nil, errors.New("JSON Error: Parameter order cannot be empty")
+	}
+	pair, err := k.assetPair(ctx, order.Pair)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.Validate(pair); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		var dat AddOrderInfo
+		err := k.queryPrivate(ctx, endpointAddOrder, order.values(), &dat)
+		if err == nil {
+			return &dat, nil
+		}
+		if k.RetryPolicy == nil || (order.UserRef == 0 && !order.ValidateOnly) || ctx.Err() != nil {
+			return nil, err
+		}
+		delay, retry := k.RetryPolicy.Backoff(attempt, time.Since(start), err)
+		if !retry {
+			return nil, err
+		}
+		k.logf("kraken: %s attempt %d failed, reconciling in %v: %v", endpointAddOrder, attempt, delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		if order.ValidateOnly {
+			continue
+		}
+		placed, lookupErr := k.ordersByUserRef(ctx, order.UserRef, start)
+		if lookupErr != nil {
+			// whether the order was placed is unknown
+			return nil, err
+		}
+		if placed != nil {
+			return placed, nil
+		}
+	}
+}
+
+// orderClockSkew is the margin given to the open time of the orders looked up
+// after a failed placement, for the clock difference with the server.
+const orderClockSkew = time.Minute
+
+// ordersByUserRef returns the orders opened with userRef since the given time,
+// as the result of their placement, or nil if there is none.
+func (k *Kraken) ordersByUserRef(ctx context.Context, userRef int32, since time.Time) (*AddOrderInfo, error) {
+	since = since.Add(-orderClockSkew)
+
+	open, err := k.OpenOrders(ctx, &OpenOrdersOptions{UserRef: userRef})
+	if err != nil {
+		return nil, err
+	}
+	closed, err := k.ClosedOrders(ctx, &ClosedOrdersOptions{
+		UserRef: userRef,
+		Start:   strconv.FormatInt(since.Unix(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orders := OrderMap{}
+	for txid, o := range *open {
+		orders[txid] = o
+	}
+	for txid, o := range closed.Closed {
+		orders[txid] = o
+	}
+
+	var info *AddOrderInfo
+	sorted := orders.Sorted()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		o := sorted[i]
+		if o.UserRef != userRef || o.OpenTime.Before(since) {
+			continue
+		}
+		if info == nil {
+			info = &AddOrderInfo{Descr: o.Descr}
+		}
+		info.TxID = append(info.TxID, o.TxID)
+	}
+	return info, nil
+}
+
+// values returns the form values of the order.
+func (o *AddOrderRequest) values() url.Values {
+	query := url.Values{}
+	query.Add("pair", o.Pair)
+	query.Add("type", o.Type)
+	query.Add("ordertype", o.OrderType)
+	query.Add("volume", o.Volume)
+	if o.Price != "" {
+		query.Add("price", o.Price)
+	}
+	if o.Price2 != "" {
+		query.Add("price2", o.Price2)
+	}
+	if o.Leverage != "" {
+		query.Add("leverage", o.Leverage)
+	}
+	if len(o.OFlags) > 0 {
+		query.Add("oflags", strings.Join(o.OFlags, ","))
+	}
+	if o.StartTime != "" {
+		query.Add("starttm", o.StartTime)
+	}
+	if o.ExpireTime != "" {
+		query.Add("expiretm", o.ExpireTime)
+	}
+	if o.UserRef != 0 {
+		query.Add("userref", strconv.FormatInt(int64(o.UserRef), 10))
+	}
+	if o.ValidateOnly {
+		query.Add("validate", "true")
+	}
+	if o.Close != nil {
+		query.Add("close[ordertype]", o.Close.OrderType)
+		if o.Close.Price != "" {
+			query.Add("close[price]", o.Close.Price)
+		}
+		if o.Close.Price2 != "" {
+			query.Add("close[price2]", o.Close.Price2)
+		}
+	}
+	return query
+}
+
+// CancelOrder cancels the open order with the given transaction id, or all
+// the open orders with the given user reference id.
+//
+// Result:
+//
+//	count = number of orders canceled
+//	pending = if set, order(s) is/are pending cancellation
+//
+// https://www.kraken.com/help/api#cancel-open-order
+func (k *Kraken) CancelOrder(ctx context.Context, txid string) (*CancelOrderInfo, error) {
+	if txid == "" {
+		return nil, errors.New("JSON Error: Parameter txid cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("txid", txid)
+
+	var dat CancelOrderInfo
+	if err := k.queryPrivate(ctx, endpointCancelOrder, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// CancelOrderByUserRef cancels all the open orders with the given user reference id.
+func (k *Kraken) CancelOrderByUserRef(ctx context.Context, userref int32) (*CancelOrderInfo, error) {
+	return k.CancelOrder(ctx, strconv.FormatInt(int64(userref), 10))
+}
+
+// CancelAll cancels all the open orders.
+//
+// https://docs.kraken.com/rest/#operation/cancelAllOrders
+func (k *Kraken) CancelAll(ctx context.Context) (*CancelOrderInfo, error) {
+	var dat CancelOrderInfo
+	if err := k.queryPrivate(ctx, endpointCancelAll, nil, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// CancelAllOrdersAfter arms the dead man's switch: all the open orders are
+// cancelled once timeout (rounded up to the second) has passed, unless the
+// timer is reset by another call before. A zero timeout disables the timer.
+//
+// The call is meant to be repeated periodically, e.g. every 15 to 30 seconds
+// with a 60 seconds timeout, so that the orders of a crashed or disconnected
+// process do not stay on the book.
+//
+// https://docs.kraken.com/rest/#operation/cancelAllOrdersAfter
+func (k *Kraken) CancelAllOrdersAfter(ctx context.Context, timeout time.Duration) (*CancelAfterInfo, error) {
+	if timeout < 0 {
+		return nil, errors.New("JSON Error: Parameter timeout cannot be negative")
+	}
+	query := url.Values{}
+	// a timeout below the second must not disable the timer
+	seconds := int64((timeout + time.Second - 1) / time.Second)
+	query.Add("timeout", strconv.FormatInt(seconds, 10))
+
+	var dat CancelAfterInfo
+	if err := k.queryPrivate(ctx, endpointCancelAfter, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// EditOrder amends the volume, prices or flags of an open order, after
+// validating them against the pair info (see EditOrderRequest.Validate).
+// The order is replaced by a new one, whose transaction id is returned in
+// the TxID of the result, OriginalTxID holding the one of the edited order.
+//
+// https://docs.kraken.com/rest/#operation/editOrder
+func (k *Kraken) EditOrder(ctx context.Context, order *EditOrderRequest) (*EditOrderInfo, error) {
+	if order == nil {
+		return nil, errors.New("JSON Error: Parameter order cannot be empty")
+	}
+	pair, err := k.assetPair(ctx, order.Pair)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.Validate(pair); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Add("txid", order.TxID)
+	query.Add("pair", order.Pair)
+	if order.UserRef != 0 {
+		query.Add("userref", strconv.FormatInt(int64(order.UserRef), 10))
+	}
+	if order.Volume != "" {
+		query.Add("volume", order.Volume)
+	}
+	if order.Price != "" {
+		query.Add("price", order.Price)
+	}
+	if order.Price2 != "" {
+		query.Add("price2", order.Price2)
+	}
+	if len(order.OFlags) > 0 {
+		query.Add("oflags", strings.Join(order.OFlags, ","))
+	}
+	if order.Deadline != "" {
+		query.Add("deadline", order.Deadline)
+	}
+	if order.CancelResponse {
+		query.Add("cancel_response", "true")
+	}
+	if order.ValidateOnly {
+		query.Add("validate", "true")
+	}
+
+	var dat EditOrderInfo
+	if err := k.queryPrivate(ctx, endpointEditOrder, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// AddOrderBatch places between 2 and AddOrderBatchLimit orders on the same
+// pair in a single call, after validating each of them against the pair
+// info. The orders' Pair may be left empty. With validateOnly set the orders
+// are only validated by the server and not submitted.
+//
+// The results are in the order of the orders, the failed ones holding an Error.
+//
+// Input:
+//
+//	deadline = RFC3339 timestamp after which the matching engine should reject the batch (optional)
+//
+// https://docs.kraken.com/rest/#operation/addOrderBatch
+func (k *Kraken) AddOrderBatch(ctx context.Context, pair string, orders []AddOrderRequest, deadline string, validateOnly bool) (*AddOrderBatchInfo, error) {
+	if len(orders) < 2 || len(orders) > AddOrderBatchLimit {
+		return nil, fmt.Errorf("JSON Error: A batch holds between 2 and %d orders, got %d", AddOrderBatchLimit, len(orders))
+	}
+	info, err := k.assetPair(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Add("pair", pair)
+	for i := range orders {
+		order := orders[i]
+		if order.Pair == "" {
+			order.Pair = pair
+		}
+		if order.Pair != pair {
+			return nil, fmt.Errorf("JSON Error: Order %d is on pair %s, not %s", i, order.Pair, pair)
+		}
+		if err := order.Validate(info); err != nil {
+			return nil, fmt.Errorf("%v (order %d)", err, i)
+		}
+		for key, v := range order.values() {
+			if key == "pair" || key == "validate" {
+				continue
+			}
+			// close[ordertype] becomes orders[i][close][ordertype]
+			key = strings.Replace(strings.TrimSuffix(key, "]"), "[", "][", 1)
+			query[fmt.Sprintf("orders[%d][%s]", i, key)] = v
+		}
+	}
+	if deadline != "" {
+		query.Add("deadline", deadline)
+	}
+	if validateOnly {
+		query.Add("validate", "true")
+	}
+
+	var dat AddOrderBatchInfo
+	if err := k.queryPrivate(ctx, endpointAddBatch, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// CancelOrderBatch cancels up to CancelOrderBatchLimit open orders, given by
+// transaction id or user reference id, in a single call.
+//
+// https://docs.kraken.com/rest/#operation/cancelOrderBatch
+func (k *Kraken) CancelOrderBatch(ctx context.Context, txids []string) (*CancelOrderInfo, error) {
+	if len(txids) == 0 || len(txids) > CancelOrderBatchLimit {
+		return nil, fmt.Errorf("JSON Error: A batch holds between 1 and %d orders, got %d", CancelOrderBatchLimit, len(txids))
+	}
+	query := url.Values{}
+	for i, txid := range txids {
+		query.Add(fmt.Sprintf("orders[%d]", i), txid)
+	}
+
+	var dat CancelOrderInfo
+	if err := k.queryPrivate(ctx, endpointCancelBatch, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// OpenOrders returns the open orders, keyed by transaction id.
+//
+// Input (options may be nil):
+//
+//	trades = whether or not to include trades in output (optional.  default = false)
+//	userref = restrict results to given user reference id (optional)
+//
+// https://www.kraken.com/help/api#get-open-orders
+func (k *Kraken) OpenOrders(ctx context.Context, options *OpenOrdersOptions) (*OrderMap, error) {
+	query := url.Values{}
+	if options != nil {
+		addOrdersQuery(query, options.Trades, options.UserRef)
+	}
+
+	var dat OpenOrdersInfo
+	if err := k.queryPrivate(ctx, endpointOpenOrders, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat.Open, nil
+}
+
+// ClosedOrders returns a page of closed orders, at most 50 of them starting
+// at options.Offset, and the total count of orders matching the criteria.
+// ClosedOrdersIterator walks all the pages.
+//
+// Input (options may be nil):
+//
+//	trades = whether or not to include trades in output (optional.  default = false)
+//	userref = restrict results to given user reference id (optional)
+//	start = starting unix timestamp or order tx id of results (optional.  exclusive)
+//	end = ending unix timestamp or order tx id of results (optional.  inclusive)
+//	ofs = result offset
+//	closetime = which time to use (optional): open, close, both (default)
+//
+// https://www.kraken.com/help/api#get-closed-orders
+func (k *Kraken) ClosedOrders(ctx context.Context, options *ClosedOrdersOptions) (*ClosedOrdersInfo, error) {
+	query := url.Values{}
+	if options != nil {
+		addOrdersQuery(query, options.Trades, options.UserRef)
+		if options.Start != "" {
+			query.Add("start", options.Start)
+		}
+		if options.End != "" {
+			query.Add("end", options.End)
+		}
+		if options.Offset != 0 {
+			query.Add("ofs", strconv.Itoa(options.Offset))
+		}
+		if options.CloseTime != "" {
+			query.Add("closetime", options.CloseTime)
+		}
+	}
+
+	var dat ClosedOrdersInfo
+	if err := k.queryPrivate(ctx, endpointClosedOrders, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// QueryOrders returns the info of the given orders, keyed by transaction id.
+// The transaction ids are sent in batches of QueryOrdersLimit.
+//
+// Input (options may be nil):
+//
+//	txid = list of transaction ids to query info about
+//	trades = whether or not to include trades in output (optional.  default = false)
+//	userref = restrict results to given user reference id (optional)
+//
+// https://www.kraken.com/help/api#query-orders-info
+func (k *Kraken) QueryOrders(ctx context.Context, txids []string, options *QueryOrdersOptions) (*OrderMap, error) {
+	if len(txids) == 0 {
+		return nil, errors.New("JSON Error: Parameter txid cannot be empty")
+	}
+
+	orders := OrderMap{}
+	for start := 0; start < len(txids); start += QueryOrdersLimit {
+		end := start + QueryOrdersLimit
+		if end > len(txids) {
+			end = len(txids)
+		}
+		query := url.Values{}
+		query.Add("txid", strings.Join(txids[start:end], ","))
+		if options != nil {
+			addOrdersQuery(query, options.Trades, options.UserRef)
+		}
+
+		var dat OrderMap
+		if err := k.queryPrivate(ctx, endpointQueryOrders, query, &dat); err != nil {
+			return nil, err
+		}
+		for txid, o := range dat {
+			orders[txid] = o
+		}
+	}
+
+	return &orders, nil
+}
+
+// addOrdersQuery adds the query parameters common to the orders requests.
+func addOrdersQuery(query url.Values, trades bool, userref int32) {
+	if trades {
+		query.Add("trades", "true")
+	}
+	if userref != 0 {
+		query.Add("userref", strconv.FormatInt(int64(userref), 10))
+	}
+}
+
+// ClosedOrdersIterator walks all the closed orders matching its options,
+// fetching the pages of ClosedOrders as needed:
+//
+//	it := k.ClosedOrdersIterator(options)
+//	for it.Next(ctx) {
+//		order := it.Order()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ClosedOrdersIterator struct {
+	k       *Kraken
+	options ClosedOrdersOptions
+	page    []Order
+	order   Order
+	seen    map[string]bool
+	done    bool
+	err     error
+}
+
+// ClosedOrdersIterator returns an iterator over the closed orders matching
+// the options (which may be nil), starting at options.Offset.
+func (k *Kraken) ClosedOrdersIterator(options *ClosedOrdersOptions) *ClosedOrdersIterator {
+	it := &ClosedOrdersIterator{k: k, seen: map[string]bool{}}
+	if options != nil {
+		it.options = *options
+	}
+	return it
+}
+
+// Next advances to the next order, fetching the next page when needed.
+// It returns false once all the orders were returned or a call failed.
+// Orders shifted into the next page by newly closed orders are returned once.
+func (it *ClosedOrdersIterator) Next(ctx context.Context) bool {
+	for {
+		for len(it.page) > 0 {
+			it.order = it.page[0]
+			it.page = it.page[1:]
+			if !it.seen[it.order.TxID] {
+				it.seen[it.order.TxID] = true
+				return true
+			}
+		}
+		if it.done || it.err != nil {
+			return false
+		}
+
+		r, err := it.k.ClosedOrders(ctx, &it.options)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = r.Closed.Sorted()
+		it.options.Offset += len(it.page)
+		if len(it.page) == 0 || it.options.Offset >= r.Count {
+			it.done = true
+		}
+	}
+}
+
+// Order returns the current order.
+func (it *ClosedOrdersIterator) Order() Order {
+	return it.order
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ClosedOrdersIterator) Err() error {
+	return it.err
+}
+
+// TradesHistory returns a page of the trades history, at most 50 trades
+// starting at options.Offset, and the total count of trades matching the criteria.
+//
+// Input (options may be nil):
+//
+//	type = type of trade (optional): all (default), any position, closed position, closing position, no position
+//	trades = whether or not to include trades related to position in output (optional.  default = false)
+//	start = starting unix timestamp or trade tx id of results (optional.  exclusive)
+//	end = ending unix timestamp or trade tx id of results (optional.  inclusive)
+//	ofs = result offset
+//
+// https://www.kraken.com/help/api#get-trades-history
+func (k *Kraken) TradesHistory(ctx context.Context, options *TradesHistoryOptions) (*TradesHistoryInfo, error) {
+	query := url.Values{}
+	if options != nil {
+		if options.Type != "" {
+			query.Add("type", options.Type)
+		}
+		if options.Trades {
+			query.Add("trades", "true")
+		}
+		if options.Start != "" {
+			query.Add("start", options.Start)
+		}
+		if options.End != "" {
+			query.Add("end", options.End)
+		}
+		if options.Offset != 0 {
+			query.Add("ofs", strconv.Itoa(options.Offset))
+		}
+	}
+
+	var dat TradesHistoryInfo
+	if err := k.queryPrivate(ctx, endpointTrades, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// QueryTrades returns the info of the given trades, keyed by transaction id.
+// The transaction ids are sent in batches of QueryTradesLimit.
+//
+// Input:
+//
+//	txid = list of transaction ids to query info about
+//	trades = whether or not to include trades related to position in output
+//
+// https://www.kraken.com/help/api#query-trades-info
+func (k *Kraken) QueryTrades(ctx context.Context, txids []string, trades bool) (*TradeInfoMap, error) {
+	if len(txids) == 0 {
+		return nil, errors.New("JSON Error: Parameter txid cannot be empty")
+	}
+
+	result := TradeInfoMap{}
+	for start := 0; start < len(txids); start += QueryTradesLimit {
+		end := start + QueryTradesLimit
+		if end > len(txids) {
+			end = len(txids)
+		}
+		query := url.Values{}
+		query.Add("txid", strings.Join(txids[start:end], ","))
+		if trades {
+			query.Add("trades", "true")
+		}
+
+		var dat TradeInfoMap
+		if err := k.queryPrivate(ctx, endpointQueryTrades, query, &dat); err != nil {
+			return nil, err
+		}
+		for txid, t := range dat {
+			result[txid] = t
+		}
+	}
+
+	return &result, nil
+}
+
+// OpenPositions returns the open margin positions, keyed by transaction id.
+//
+// Input:
+//
+//	txid = list of transaction ids to restrict output to (optional.  all if empty)
+//	docalcs = whether or not to include profit/loss calculations
+//
+// https://www.kraken.com/help/api#get-open-positions
+func (k *Kraken) OpenPositions(ctx context.Context, txids []string, docalcs bool) (*PositionMap, error) {
+	query := url.Values{}
+	if len(txids) > 0 {
+		query.Add("txid", strings.Join(txids, ","))
+	}
+	if docalcs {
+		query.Add("docalcs", "true")
+	}
+
+	var dat PositionMap
+	if err := k.queryPrivate(ctx, endpointPositions, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// Ledgers returns a page of ledger entries, at most 50 of them starting at
+// options.Offset, and the total count of entries matching the criteria.
+// WalkLedgers walks all the pages.
+//
+// Input (options may be nil):
+//
+//	aclass = asset class (optional): currency (default)
+//	asset = comma delimited list of assets to restrict output to (optional.  default = all)
+//	type = type of ledger to retrieve (optional): all (default), deposit, withdrawal, trade, margin, ...
+//	start = starting unix timestamp or ledger id of results (optional.  exclusive)
+//	end = ending unix timestamp or ledger id of results (optional.  inclusive)
+//	ofs = result offset
+//
+// https://www.kraken.com/help/api#get-ledgers-info
+func (k *Kraken) Ledgers(ctx context.Context, options *LedgersOptions) (*LedgersInfo, error) {
+	query := url.Values{}
+	if options != nil {
+		if options.AssetClass != "" {
+			query.Add("aclass", options.AssetClass)
+		}
+		if len(options.Assets) > 0 {
+			query.Add("asset", strings.Join(options.Assets, ","))
+		}
+		if options.Type != "" {
+			query.Add("type", options.Type)
+		}
+		if options.Start != "" {
+			query.Add("start", options.Start)
+		}
+		if options.End != "" {
+			query.Add("end", options.End)
+		}
+		if options.Offset != 0 {
+			query.Add("ofs", strconv.Itoa(options.Offset))
+		}
+	}
+
+	var dat LedgersInfo
+	if err := k.queryPrivate(ctx, endpointLedgers, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// QueryLedgers returns the given ledger entries, keyed by ledger id.
+// The ledger ids are sent in batches of QueryLedgersLimit.
+//
+// https://www.kraken.com/help/api#query-ledgers
+func (k *Kraken) QueryLedgers(ctx context.Context, ids []string) (*LedgerMap, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("JSON Error: Parameter id cannot be empty")
+	}
+
+	result := LedgerMap{}
+	for start := 0; start < len(ids); start += QueryLedgersLimit {
+		end := start + QueryLedgersLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		query := url.Values{}
+		query.Add("id", strings.Join(ids[start:end], ","))
+
+		var dat LedgerMap
+		if err := k.queryPrivate(ctx, endpointQueryLedgers, query, &dat); err != nil {
+			return nil, err
+		}
+		for id, l := range dat {
+			result[id] = l
+		}
+	}
+
+	return &result, nil
+}
+
+// WalkLedgers pages through all the ledger entries matching the options
+// (which may be nil), starting at options.Offset, and calls fn for every
+// entry, the most recent first. Entries shifted into the next page by new
+// ones are passed once. An error returned by fn stops the walk and is
+// returned.
+func (k *Kraken) WalkLedgers(ctx context.Context, options *LedgersOptions, fn func(LedgerEntry) error) error {
+	var opts LedgersOptions
+	if options != nil {
+		opts = *options
+	}
+
+	seen := map[string]bool{}
+	for {
+		r, err := k.Ledgers(ctx, &opts)
+		if err != nil {
+			return err
+		}
+		page := r.Ledger.Sorted()
+		for _, l := range page {
+			if seen[l.ID] {
+				continue
+			}
+			seen[l.ID] = true
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		opts.Offset += len(page)
+		if len(page) == 0 || opts.Offset >= r.Count {
+			return nil
+		}
+	}
+}
+
+// TradeVolume returns the 30 days trade volume of the account and,
+// for the given pairs, the taker and maker fee tiers.
+//
+// Input:
+//
+//	pair = list of asset pairs to get fee info on (optional)
+//	fee-info = whether or not to include fee info in results, set when pairs are given
+//
+// https://www.kraken.com/help/api#get-trade-volume
+func (k *Kraken) TradeVolume(ctx context.Context, pairs []string) (*TradeVolumeInfo, error) {
+	query := url.Values{}
+	if len(pairs) > 0 {
+		query.Add("pair", strings.Join(pairs, ","))
+		query.Add("fee-info", "true")
+	}
+
+	var dat TradeVolumeInfo
+	if err := k.queryPrivate(ctx, endpointTradeVolume, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// DepositMethods returns the deposit methods available for the asset,
+// given by its id (as in AssetsInfoMap) or altname.
+//
+// Input:
+//
+//	aclass = asset class (optional): currency (default)
+//	asset = asset being deposited
+//
+// https://www.kraken.com/help/api#deposit-methods
+func (k *Kraken) DepositMethods(ctx context.Context, asset string) ([]DepositMethod, error) {
+	if asset == "" {
+		return nil, errors.New("JSON Error: Parameter asset cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+
+	var dat []DepositMethod
+	if err := k.queryPrivate(ctx, endpointDepositMethods, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// DepositAddresses returns the deposit addresses of the asset for the
+// given method, generating a new one if newAddress is set. A call generating
+// a new address is never retried.
+//
+// Input:
+//
+//	asset = asset being deposited
+//	method = name of the deposit method
+//	new = whether or not to generate a new address (optional.  default = false)
+//
+// https://www.kraken.com/help/api#deposit-addresses
+func (k *Kraken) DepositAddresses(ctx context.Context, asset, method string, newAddress bool) ([]DepositAddress, error) {
+	if asset == "" || method == "" {
+		return nil, errors.New("JSON Error: Parameters asset and method cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	query.Add("method", method)
+	if newAddress {
+		query.Add("new", "true")
+	}
+
+	var dat []DepositAddress
+	if err := k.queryPrivate(ctx, endpointDepositAddresses, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// DepositStatus returns the status of the recent deposits of the asset,
+// optionally restricted to the given method.
+//
+// Input:
+//
+//	asset = asset being deposited
+//	method = name of the deposit method (optional)
+//
+// https://www.kraken.com/help/api#deposit-status
+func (k *Kraken) DepositStatus(ctx context.Context, asset, method string) ([]TransferStatus, error) {
+	if asset == "" {
+		return nil, errors.New("JSON Error: Parameter asset cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	if method != "" {
+		query.Add("method", method)
+	}
+
+	var dat []TransferStatus
+	if err := k.queryPrivate(ctx, endpointDepositStatus, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// ErrWithdrawNotConfirmed is returned by Withdraw for a request which is not Confirmed.
+var ErrWithdrawNotConfirmed = errors.New("Private API Error: Withdrawal is not confirmed")
+
+// WithdrawInfo returns the method, limit and fee of withdrawing the amount
+// of the asset to the given withdrawal key.
+//
+// Input:
+//
+//	asset = asset being withdrawn
+//	key = withdrawal key name, as set up on your account
+//	amount = amount to withdraw
+//
+// https://www.kraken.com/help/api#get-withdrawal-info
+func (k *Kraken) WithdrawInfo(ctx context.Context, asset, key, amount string) (*WithdrawInfo, error) {
+	if asset == "" || key == "" || amount == "" {
+		return nil, errors.New("JSON Error: Parameters asset, key and amount cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	query.Add("key", key)
+	query.Add("amount", amount)
+
+	var dat WithdrawInfo
+	if err := k.queryPrivate(ctx, endpointWithdrawInfo, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return &dat, nil
+}
+
+// Withdraw withdraws funds to the given withdrawal key and returns the
+// reference id of the withdrawal. The request must be Confirmed, otherwise
+// nothing is sent and ErrWithdrawNotConfirmed is returned.
+//
+// https://www.kraken.com/help/api#withdraw-funds
+func (k *Kraken) Withdraw(ctx context.Context, request *WithdrawRequest) (string, error) {
+	if request == nil || request.Asset == "" || request.Key == "" || request.Amount == "" {
+		return "", errors.New("JSON Error: Parameters asset, key and amount cannot be empty")
+	}
+	if !request.Confirmed {
+		return "", ErrWithdrawNotConfirmed
+	}
+	query := url.Values{}
+	query.Add("asset", request.Asset)
+	query.Add("key", request.Key)
+	query.Add("amount", request.Amount)
+
+	var dat WithdrawReference
+	if err := k.queryPrivate(ctx, endpointWithdraw, query, &dat); err != nil {
+		return "", err
+	}
+
+	return dat.RefID, nil
+}
+
+// WithdrawStatus returns the status of the recent withdrawals of the asset,
+// optionally restricted to the given method.
+//
+// Input:
+//
+//	asset = asset being withdrawn
+//	method = withdrawal method name (optional)
+//
+// https://www.kraken.com/help/api#withdraw-status
+func (k *Kraken) WithdrawStatus(ctx context.Context, asset, method string) ([]TransferStatus, error) {
+	if asset == "" {
+		return nil, errors.New("JSON Error: Parameter asset cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	if method != "" {
+		query.Add("method", method)
+	}
+
+	var dat []TransferStatus
+	if err := k.queryPrivate(ctx, endpointWithdrawStatus, query, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// WithdrawCancel requests the cancellation of a recent withdrawal, if it has
+// not already been successfully processed, and reports whether it succeeded.
+//
+// Input:
+//
+//	asset = asset being withdrawn
+//	refid = withdrawal reference id
+//
+// https://www.kraken.com/help/api#withdraw-cancel
+func (k *Kraken) WithdrawCancel(ctx context.Context, asset, refid string) (bool, error) {
+	if asset == "" || refid == "" {
+		return false, errors.New("JSON Error: Parameters asset and refid cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	query.Add("refid", refid)
+
+	var dat bool
+	if err := k.queryPrivate(ctx, endpointWithdrawCancel, query, &dat); err != nil {
+		return false, err
+	}
+
+	return dat, nil
+}
+
+// StakingAssets returns the assets the account can stake.
+//
+// https://docs.kraken.com/rest/#operation/getStakingAssetInfo
+func (k *Kraken) StakingAssets(ctx context.Context) ([]StakingAsset, error) {
+	var dat []StakingAsset
+	if err := k.queryPrivate(ctx, endpointStakingAssets, nil, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// Stake stakes the amount of the asset with the given method (see
+// StakingAsset.Method) and returns the reference id of the transaction.
+//
+// Input:
+//
+//	asset = asset to stake
+//	amount = amount of the asset to stake
+//	method = name of the staking option to use
+//
+// https://docs.kraken.com/rest/#operation/stake
+func (k *Kraken) Stake(ctx context.Context, asset, amount, method string) (string, error) {
+	if asset == "" || amount == "" || method == "" {
+		return "", errors.New("JSON Error: Parameters asset, amount and method cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	query.Add("amount", amount)
+	query.Add("method", method)
+
+	var dat StakingReference
+	if err := k.queryPrivate(ctx, endpointStake, query, &dat); err != nil {
+		return "", err
+	}
+
+	return dat.RefID, nil
+}
+
+// Unstake unstakes the amount of the staked asset (e.g. DOT.S) and returns
+// the reference id of the transaction.
+//
+// Input:
+//
+//	asset = staked asset to unstake
+//	amount = amount of the asset to unstake
+//
+// https://docs.kraken.com/rest/#operation/unstake
+func (k *Kraken) Unstake(ctx context.Context, asset, amount string) (string, error) {
+	if asset == "" || amount == "" {
+		return "", errors.New("JSON Error: Parameters asset and amount cannot be empty")
+	}
+	query := url.Values{}
+	query.Add("asset", asset)
+	query.Add("amount", amount)
+
+	var dat StakingReference
+	if err := k.queryPrivate(ctx, endpointUnstake, query, &dat); err != nil {
+		return "", err
+	}
+
+	return dat.RefID, nil
+}
+
+// StakingPending returns the staking transactions which are not completed yet.
+//
+// https://docs.kraken.com/rest/#operation/getStakingPendingDeposits
+func (k *Kraken) StakingPending(ctx context.Context) ([]StakingTransaction, error) {
+	var dat []StakingTransaction
+	if err := k.queryPrivate(ctx, endpointStakingPending, nil, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// StakingTransactions returns the recent staking transactions.
+//
+// https://docs.kraken.com/rest/#operation/getStakingTransactions
+func (k *Kraken) StakingTransactions(ctx context.Context) ([]StakingTransaction, error) {
+	var dat []StakingTransaction
+	if err := k.queryPrivate(ctx, endpointStakingTransactions, nil, &dat); err != nil {
+		return nil, err
+	}
+
+	return dat, nil
+}
+
+// WebSocketsTokenRefreshMargin is how long before its expiry a cached
+// WebSocket token is replaced by a new one.
+const WebSocketsTokenRefreshMargin = time.Minute
+
+// wsTokenCache keeps the last WebSocket token until it is about to expire.
+type wsTokenCache struct {
+	mu    sync.Mutex
+	token *WebSocketsToken
+	// fetching is closed once the token being requested is cached,
+	// nil if none is being requested.
+	fetching chan struct{}
+}
+
+// GetWebSocketsToken returns a token for connecting to the private WebSocket
+// feeds. The token is cached and a new one is requested once the cached one
+// is within WebSocketsTokenRefreshMargin of its expiry. Concurrent calls share
+// the request of a new token.
+//
+// Note: the token does not expire once a connection using it is established.
+//
+// https://docs.kraken.com/rest/#operation/getWebsocketsToken
+func (k *Kraken) GetWebSocketsToken(ctx context.Context) (*WebSocketsToken, error) {
+	for {
+		k.wsToken.mu.Lock()
+		if t := k.wsToken.token; t != nil && time.Now().Add(WebSocketsTokenRefreshMargin).Before(t.ExpireTime) {
+			cached := *t
+			k.wsToken.mu.Unlock()
+			return &cached, nil
+		}
+		fetching := k.wsToken.fetching
+		if fetching == nil {
+			fetching = make(chan struct{})
+			k.wsToken.fetching = fetching
+			k.wsToken.mu.Unlock()
+			return k.fetchWebSocketsToken(ctx, fetching)
+		}
+		k.wsToken.mu.Unlock()
+
+		// the token requested by another call is used, or requested again
+		// if that call failed
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fetchWebSocketsToken requests a new WebSocket token and caches it, closing
+// fetching once done.
+func (k *Kraken) fetchWebSocketsToken(ctx context.Context, fetching chan struct{}) (*WebSocketsToken, error) {
+	requested := time.Now()
+	var dat WebSocketsToken
+	err := k.queryPrivate(ctx, endpointWSToken, nil, &dat)
+
+	k.wsToken.mu.Lock()
+	defer k.wsToken.mu.Unlock()
+	k.wsToken.fetching = nil
+	close(fetching)
+	if err != nil {
+		return nil, err
+	}
+	dat.ExpireTime = requested.Add(time.Duration(dat.Expires) * time.Second)
+	cached := dat
+	k.wsToken.token = &cached
+
+	return &dat, nil
+}
@@ -0,0 +1,1097 @@
+package kraken
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test credentials, the secret being the one of Kraken's signing example.
+const (
+	testAPIKey    = "test-api-key"
+	testAPISecret = "kQH5HW/8p1uGOVjbgWA7FunAmGO8lsSUXNsu3eow76sz84Q18fWxnyRzBHCd3pd5nE9qa99HAZtuZuj6F1huXg=="
+)
+
+// newPrivateTestServer starts a local stand-in for the Kraken API which
+// serves the public routes as is, checks the signature of the private requests, hands their form to check
+// (if not nil), and serves the given JSON bodies keyed by request path.
+// check can replace the served body by updating routes. Bodies which are
+// not JSON objects are served with their detected content type.
+func newPrivateTestServer(t *testing.T, routes map[string]string, check func(path string, form url.Values)) (*Kraken, func()) {
+	secret, _ := base64.StdEncoding.DecodeString(testAPISecret)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(r.URL.Path, "/private/") {
+			io.WriteString(w, body)
+			return
+		}
+		postData, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		form, err := url.ParseQuery(string(postData))
+		if err != nil {
+			t.Error(err)
+		}
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("Private request should be a form POST, got: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get("API-Key") != testAPIKey {
+			t.Errorf("API-Key expected: %s, got: %s", testAPIKey, r.Header.Get("API-Key"))
+		}
+		expected := signature(secret, r.URL.Path, form.Get("nonce"), string(postData))
+		if r.Header.Get("API-Sign") != expected {
+			t.Errorf("API-Sign mismatch for %s", r.URL.Path)
+		}
+		if check != nil {
+			check(r.URL.Path, form)
+		}
+		// check may have replaced the body according to the form.
+		body = routes[r.URL.Path]
+		if !strings.HasPrefix(body, "{") {
+			w.Header().Set("Content-Type", http.DetectContentType([]byte(body)))
+		}
+		io.WriteString(w, body)
+	}))
+
+	k := NewClient(WithBaseURL(srv.URL), WithCredentials(testAPIKey, testAPISecret))
+	return k, srv.Close
+}
+
+func Test_signature(t *testing.T) {
+	// https://docs.kraken.com/rest/#section/Authentication/Headers-and-Signature
+	const (
+		nonce    = "1616492376594"
+		postData = "nonce=1616492376594&ordertype=limit&pair=XBTUSD&price=37500&type=buy&volume=1.25"
+		path     = "/0/private/AddOrder"
+		expected = "4/dpxb3iT4tp/ZCVEwSnEsLxx0bqyhLpdfOpc6fn7OR8+UClSV5n9E6aSS8MPtnRfp32bAb0nmbRn6H8ndwLUQ=="
+	)
+	secret, err := base64.StdEncoding.DecodeString(testAPISecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := signature(secret, path, nonce, postData); got != expected {
+		t.Errorf("API-Sign expected: %s, got: %s", expected, got)
+	}
+}
+
+func Test_nonceGenerator(t *testing.T) {
+	var g nonceGenerator
+	last := g.next()
+	for i := 0; i < 1000; i++ {
+		n := g.next()
+		if n <= last {
+			t.Fatalf("Nonce should increase, got %d after %d", n, last)
+		}
+		last = n
+	}
+}
+
+func Test_Kraken_queryPrivate(t *testing.T) {
+	var nonces []string
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/Test": `{"error":[],"result":{"status":"ok"}}`,
+	}, func(path string, form url.Values) {
+		nonces = append(nonces, form.Get("nonce"))
+		if form.Get("asset") != "XXBT" {
+			t.Errorf("Form value asset expected: XXBT, got: %s", form.Get("asset"))
+		}
+	})
+	defer closeServer()
+
+	for i := 0; i < 2; i++ {
+		var r struct{ Status string }
+		if err := k.queryPrivate(context.Background(), "Test", url.Values{"asset": {"XXBT"}}, &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != "ok" {
+			t.Errorf("Status expected: ok, got: %s", r.Status)
+		}
+	}
+	if len(nonces) != 2 || nonces[0] >= nonces[1] {
+		t.Errorf("Every call should be sent with a greater nonce, got: %v", nonces)
+	}
+
+	k.Secret = ""
+	if err := k.queryPrivate(context.Background(), "Test", nil, nil); err == nil {
+		t.Error("queryPrivate should fail without credentials")
+	}
+	k.Secret = "not base64!"
+	if err := k.queryPrivate(context.Background(), "Test", nil, nil); err == nil {
+		t.Error("queryPrivate should fail with an invalid secret")
+	}
+}
+
+func Test_Kraken_Balance(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/Balance": `{"error":[],"result":{"ZEUR":"504.8617","XXBT":"1.0215000000","XETH":"0.0000000000"}}`,
+	}, nil)
+	defer closeServer()
+
+	r, err := k.Balance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]string{
+		"ZEUR": "504.8617",
+		"XXBT": "1.0215000000",
+		"XETH": "0.0000000000",
+	}
+	for asset, v := range testCases {
+		if (*r)[asset] != v {
+			t.Errorf("%s balance expected: %s, got: %s", asset, v, (*r)[asset])
+		}
+	}
+}
+
+func Test_Kraken_TradeBalance(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/TradeBalance": `{"error":[],"result":{"eb":"1101.3425","tb":"392.2264","m":"7.0354","n":"-10.0232",
+		"c":"21.1063","v":"31.1297","e":"382.2032","mf":"375.1678","ml":"5432.57"}}`,
+	}, func(path string, form url.Values) {
+		if form.Get("asset") != "ZEUR" || form.Get("aclass") != "" {
+			t.Errorf("Unexpected trade balance options: %v", form)
+		}
+	})
+	defer closeServer()
+
+	r, err := k.TradeBalance(context.Background(), &TradeBalanceOptions{Asset: "ZEUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.EquivalentBalance != "1101.3425" || r.Equity != "382.2032" || r.MarginLevel != "5432.57" {
+		t.Errorf("Unexpected trade balance: %+v", *r)
+	}
+}
+
+func Test_AddOrderRequest_Validate(t *testing.T) {
+	pair := &AssetPairInfo{
+		Altname:      "XBTEUR",
+		PairDecimals: 1,
+		LotDecimals:  8,
+		LeverageBuy:  []byte{2, 3},
+		LeverageSell: []byte{2},
+	}
+	testCases := []struct {
+		order AddOrderRequest
+		valid bool
+	}{
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1.25"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "37500.5", Volume: "1.25"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "37500.55", Volume: "1.25"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Volume: "1.25"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0.123456789"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0", Leverage: "none"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0", Leverage: "2"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeMarket, Volume: "0.000", Leverage: "2:1"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0.", Leverage: "2"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "-0", Leverage: "2"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: "hold", OrderType: OrderTypeMarket, Volume: "1"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: "iceberg", Volume: "1"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeStopLossLimit, Price: "30000", Price2: "29900", Volume: "1"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeStopLossLimit, Price: "30000", Volume: "1"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeStopLoss, Price: "-5%", Volume: "1"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1", Leverage: "3:1"}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeMarket, Volume: "1", Leverage: "3"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "100", Volume: "1", OFlags: []string{OrderFlagPost, OrderFlagFeeInQuote}}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "100", Volume: "1", OFlags: []string{OrderFlagFeeInBase, OrderFlagFeeInQuote}}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1", OFlags: []string{OrderFlagPost}}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1", OFlags: []string{"fok"}}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1", Close: &CloseOrder{OrderType: OrderTypeLimit, Price: "40000"}}, true},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1", Close: &CloseOrder{OrderType: OrderTypeMarket}}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "NaN"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "Inf"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1e-12"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "0x1p-3"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "1."}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeMarket, Volume: "-1"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "+-5", Volume: "1"}, false},
+		{AddOrderRequest{Pair: "XBTEUR", Type: OrderBuy, OrderType: OrderTypeLimit, Price: "#2.5", Volume: "1"}, true},
+	}
+
+	for i, tc := range testCases {
+		err := tc.order.Validate(pair)
+		if tc.valid && err != nil {
+			t.Errorf("Order %d should be valid, got: %v", i, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("Order %d should be invalid", i)
+		}
+	}
+}
+
+func Test_Kraken_assetPair(t *testing.T) {
+	routes := map[string]string{
+		"/0/public/AssetPairs": `{"error":[],"result":{}}`,
+	}
+	var fetches int
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {})
+	defer closeServer()
+	k.Client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		fetches++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	if _, err := k.assetPair(context.Background(), "XBTEUR"); !errors.Is(err, ErrUnknownAssetPair) {
+		t.Errorf("Expected ErrUnknownAssetPair, got: %v", err)
+	}
+
+	// a pair listed since is found once the cache is old enough
+	routes["/0/public/AssetPairs"] = publicRoutes["/0/public/AssetPairs"]
+	if _, err := k.assetPair(context.Background(), "XBTEUR"); err == nil || fetches != 1 {
+		t.Errorf("A recent cache should not be fetched again, got: %v, %d fetches", err, fetches)
+	}
+	k.pairs.updated = time.Now().Add(-2 * pairCacheMinAge)
+	if info, err := k.assetPair(context.Background(), "XBTEUR"); err != nil || info.PairDecimals != 1 || fetches != 2 {
+		t.Errorf("The pair should be found after fetching again, got: %v %v, %d fetches", info, err, fetches)
+	}
+
+	// the cache expires after PairCacheTTL
+	if _, err := k.assetPair(context.Background(), "XXBTZEUR"); err != nil || fetches != 2 {
+		t.Errorf("The cached pairs should be used, got: %v, %d fetches", err, fetches)
+	}
+	k.pairs.updated = time.Now().Add(-PairCacheTTL - time.Second)
+	if _, err := k.assetPair(context.Background(), "XXBTZEUR"); err != nil || fetches != 3 {
+		t.Errorf("The expired pairs should be fetched again, got: %v, %d fetches", err, fetches)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_Kraken_AddOrder(t *testing.T) {
+	routes := map[string]string{
+		"/0/public/AssetPairs": publicRoutes["/0/public/AssetPairs"],
+		"/0/private/AddOrder": `{"error":[],"result":{"descr":{"order":"buy 1.25000000 XBTEUR @ limit 37500.0","close":"close position @ limit 40000.0"},
+		"txid":["OUF4EM-FRGI2-MQMWZD"]}}`,
+	}
+	var calls int
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		calls++
+		expected := url.Values{
+			"pair":             {"XBTEUR"},
+			"type":             {"buy"},
+			"ordertype":        {"limit"},
+			"price":            {"37500.0"},
+			"volume":           {"1.25"},
+			"oflags":           {"post,fciq"},
+			"userref":          {"42"},
+			"close[ordertype]": {"limit"},
+			"close[price]":     {"40000.0"},
+		}
+		for key, v := range expected {
+			if form.Get(key) != v[0] {
+				t.Errorf("Form value %s expected: %s, got: %s", key, v[0], form.Get(key))
+			}
+		}
+	})
+	defer closeServer()
+
+	order := &AddOrderRequest{
+		Pair:      "XBTEUR",
+		Type:      OrderBuy,
+		OrderType: OrderTypeLimit,
+		Price:     "37500.0",
+		Volume:    "1.25",
+		OFlags:    []string{OrderFlagPost, OrderFlagFeeInQuote},
+		UserRef:   42,
+		Close:     &CloseOrder{OrderType: OrderTypeLimit, Price: "40000.0"},
+	}
+	r, err := k.AddOrder(context.Background(), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.TxID) != 1 || r.TxID[0] != "OUF4EM-FRGI2-MQMWZD" {
+		t.Errorf("Unexpected txid: %v", r.TxID)
+	}
+	if r.Descr.Order != "buy 1.25000000 XBTEUR @ limit 37500.0" {
+		t.Errorf("Unexpected order description: %s", r.Descr.Order)
+	}
+
+	order.Price = "37500.05"
+	if _, err := k.AddOrder(context.Background(), order); err == nil {
+		t.Error("AddOrder should reject a price with too many decimals")
+	}
+	order.Pair = "XBTJPY"
+	if _, err := k.AddOrder(context.Background(), order); err == nil {
+		t.Error("AddOrder should reject an unknown pair")
+	}
+	if calls != 1 {
+		t.Errorf("Invalid orders should not be sent, got %d calls", calls)
+	}
+}
+
+func Test_Kraken_CancelOrder(t *testing.T) {
+	var txids []string
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/CancelOrder": `{"error":[],"result":{"count":2,"pending":true}}`,
+		"/0/private/CancelAll":   `{"error":[],"result":{"count":4}}`,
+	}, func(path string, form url.Values) {
+		txids = append(txids, form.Get("txid"))
+	})
+	defer closeServer()
+
+	r, err := k.CancelOrder(context.Background(), "OYVGEW-VYV5B-UUEXSK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != 2 || !r.Pending {
+		t.Errorf("Unexpected cancel result: %+v", *r)
+	}
+	if _, err := k.CancelOrderByUserRef(context.Background(), 42); err != nil {
+		t.Fatal(err)
+	}
+	if len(txids) != 2 || txids[0] != "OYVGEW-VYV5B-UUEXSK" || txids[1] != "42" {
+		t.Errorf("Unexpected txid values: %v", txids)
+	}
+
+	r, err = k.CancelAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != 4 || r.Pending {
+		t.Errorf("Unexpected cancel all result: %+v", *r)
+	}
+}
+
+func Test_Kraken_CancelAllOrdersAfter(t *testing.T) {
+	var timeout string
+	routes := map[string]string{
+		"/0/private/CancelAllOrdersAfter": `{"error":[],"result":{"currentTime":"2021-03-24T17:41:56Z","triggerTime":"2021-03-24T17:42:56Z"}}`,
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		timeout = form.Get("timeout")
+	})
+	defer closeServer()
+
+	r, err := k.CancelAllOrdersAfter(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeout != "60" {
+		t.Errorf("Timeout expected: 60, got: %s", timeout)
+	}
+	if r.TriggerTime.Sub(r.CurrentTime) != time.Minute {
+		t.Errorf("Unexpected trigger time: %v", r.TriggerTime)
+	}
+
+	for _, tc := range []struct {
+		timeout  time.Duration
+		expected string
+	}{
+		{500 * time.Millisecond, "1"},
+		{time.Nanosecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	} {
+		if _, err := k.CancelAllOrdersAfter(context.Background(), tc.timeout); err != nil || timeout != tc.expected {
+			t.Errorf("%v: timeout expected: %s, got: %s %v", tc.timeout, tc.expected, timeout, err)
+		}
+	}
+
+	routes["/0/private/CancelAllOrdersAfter"] = `{"error":[],"result":{"currentTime":"2021-03-24T17:41:56Z","triggerTime":"0"}}`
+	r, err = k.CancelAllOrdersAfter(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeout != "0" || !r.TriggerTime.IsZero() {
+		t.Errorf("A zero timeout should disable the timer, got: %s, %v", timeout, r.TriggerTime)
+	}
+}
+
+// testOrdersPage returns a ClosedOrders result holding the orders
+// [start, end) of count orders, order i being opened at 1616665000+i.
+func testOrdersPage(start, end, count int) string {
+	var entries []string
+	for i := start; i < end; i++ {
+		entries = append(entries, fmt.Sprintf(`"O%06d":{"refid":null,"userref":0,"status":"closed",
+		"opentm":%d.5,"starttm":0,"expiretm":0,"closetm":%d.25,"descr":{"pair":"XBTEUR","type":"buy",
+		"ordertype":"limit","price":"30000.0","price2":"0","leverage":"none","order":"buy 1.00000000 XBTEUR @ limit 30000.0","close":""},
+		"vol":"1.00000000","vol_exec":"1.00000000","cost":"30000.0","fee":"48.0","price":"30000.0",
+		"stopprice":"0.00000","limitprice":"0.00000","misc":"","oflags":"fciq","reason":null}`, i, 1616665000+i, 1616665100+i))
+	}
+	return fmt.Sprintf(`{"error":[],"result":{"closed":{%s},"count":%d}}`, strings.Join(entries, ","), count)
+}
+
+func Test_Kraken_OpenOrders(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/OpenOrders": `{"error":[],"result":{"open":{"OQCLML-BW3P3-BUCMWZ":{"refid":null,"userref":42,
+		"status":"open","opentm":1616666559.8974,"starttm":0,"expiretm":0,"descr":{"pair":"XBTUSD","type":"buy",
+		"ordertype":"limit","price":"30010.0","price2":"0","leverage":"none","order":"buy 1.25000000 XBTUSD @ limit 30010.0",
+		"close":""},"vol":"1.25000000","vol_exec":"0.37500000","cost":"11253.7","fee":"0.00000","price":"30010.0",
+		"stopprice":"0.00000","limitprice":"0.00000","misc":"","oflags":"fciq","trades":["TCCCTY-WE2O6-P3NB37"]}}}}`,
+	}, func(path string, form url.Values) {
+		if form.Get("trades") != "true" || form.Get("userref") != "42" {
+			t.Errorf("Unexpected open orders options: %v", form)
+		}
+	})
+	defer closeServer()
+
+	r, err := k.OpenOrders(context.Background(), &OpenOrdersOptions{Trades: true, UserRef: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, ok := (*r)["OQCLML-BW3P3-BUCMWZ"]
+	if !ok {
+		t.Fatalf("Open order is missing: %v", *r)
+	}
+	if o.TxID != "OQCLML-BW3P3-BUCMWZ" || o.Status != OrderStatusOpen || o.UserRef != 42 {
+		t.Errorf("Unexpected order: %+v", o)
+	}
+	if o.Descr.Pair != "XBTUSD" || o.Descr.OrderType != OrderTypeLimit || o.VolumeExec != "0.37500000" {
+		t.Errorf("Unexpected order details: %+v", o)
+	}
+	if !o.OpenTime.Equal(time.Unix(1616666559, 897400000)) || !o.StartTime.IsZero() || !o.CloseTime.IsZero() {
+		t.Errorf("Unexpected order times: %v, %v, %v", o.OpenTime, o.StartTime, o.CloseTime)
+	}
+	if len(o.Trades) != 1 || o.Trades[0] != "TCCCTY-WE2O6-P3NB37" {
+		t.Errorf("Unexpected order trades: %v", o.Trades)
+	}
+}
+
+func Test_Kraken_ClosedOrdersIterator(t *testing.T) {
+	const count = 120
+	routes := map[string]string{"/0/private/ClosedOrders": ""}
+	var offsets []string
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		if form.Get("closetime") != "close" {
+			t.Errorf("closetime expected: close, got: %s", form.Get("closetime"))
+		}
+		offsets = append(offsets, form.Get("ofs"))
+		ofs, _ := strconv.Atoi(form.Get("ofs"))
+		end := ofs + 50
+		if end > count {
+			end = count
+		}
+		// newest orders first, as Kraken does
+		routes[path] = testOrdersPage(count-end, count-ofs, count)
+	})
+	defer closeServer()
+
+	r, err := k.ClosedOrders(context.Background(), &ClosedOrdersOptions{CloseTime: "close"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != count || len(r.Closed) != 50 {
+		t.Errorf("Expected a page of 50 out of %d orders, got %d out of %d", count, len(r.Closed), r.Count)
+	}
+	if o := r.Closed["O000119"]; !o.CloseTime.Equal(time.Unix(1616665219, 250000000)) {
+		t.Errorf("Unexpected close time: %v", o.CloseTime)
+	}
+
+	offsets = nil
+	it := k.ClosedOrdersIterator(&ClosedOrdersOptions{CloseTime: "close"})
+	var n int
+	for it.Next(context.Background()) {
+		expected := fmt.Sprintf("O%06d", count-1-n)
+		if it.Order().TxID != expected {
+			t.Errorf("Order %d expected: %s, got: %s", n, expected, it.Order().TxID)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != count {
+		t.Errorf("Expected %d orders, got: %d", count, n)
+	}
+	if strings.Join(offsets, ",") != ",50,100" {
+		t.Errorf("Unexpected offsets: %v", offsets)
+	}
+}
+
+func Test_Kraken_QueryOrders(t *testing.T) {
+	var batches []int
+	routes := map[string]string{"/0/private/QueryOrders": ""}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		txids := strings.Split(form.Get("txid"), ",")
+		batches = append(batches, len(txids))
+		var entries []string
+		for _, txid := range txids {
+			entries = append(entries, fmt.Sprintf(`"%s":{"status":"closed","opentm":1616665000,"descr":{},"vol":"1"}`, txid))
+		}
+		routes[path] = fmt.Sprintf(`{"error":[],"result":{%s}}`, strings.Join(entries, ","))
+	})
+	defer closeServer()
+
+	var txids []string
+	for i := 0; i < 70; i++ {
+		txids = append(txids, fmt.Sprintf("O%06d", i))
+	}
+	r, err := k.QueryOrders(context.Background(), txids, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*r) != 70 || (*r)["O000069"].TxID != "O000069" {
+		t.Errorf("Expected 70 orders, got: %d", len(*r))
+	}
+	if len(batches) != 2 || batches[0] != QueryOrdersLimit || batches[1] != 20 {
+		t.Errorf("Unexpected batches: %v", batches)
+	}
+	if _, err := k.QueryOrders(context.Background(), nil, nil); err == nil {
+		t.Error("QueryOrders should fail without txids")
+	}
+}
+
+func Test_Kraken_TradesHistory(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/TradesHistory": `{"error":[],"result":{"trades":{
+		"THVRQM-33VKH-UCI7BS":{"ordertxid":"OQCLML-BW3P3-BUCMWZ","postxid":"TKH2SE-M7IF5-CFI7LT","pair":"XXBTZUSD",
+		"time":1616667796.8802,"type":"buy","ordertype":"limit","price":"30010.00000","cost":"600.20000","fee":"0.00000",
+		"vol":"0.02000000","margin":"0.00000","misc":""},
+		"TCWJEG-FL4SZ-3FKGH6":{"ordertxid":"OQCLML-BW3P3-BUCMWZ","postxid":"TKH2SE-M7IF5-CFI7LT","pair":"XXBTZUSD",
+		"time":1616667769.6396,"type":"buy","ordertype":"limit","price":"30010.00000","cost":"300.10000","fee":"0.00000",
+		"vol":"0.01000000","margin":"0.00000","misc":""}},"count":2346}}`,
+		"/0/private/QueryTrades": `{"error":[],"result":{"THVRQM-33VKH-UCI7BS":{"ordertxid":"OQCLML-BW3P3-BUCMWZ",
+		"postxid":"TKH2SE-M7IF5-CFI7LT","pair":"XXBTZUSD","time":1616667796.8802,"type":"buy","ordertype":"limit",
+		"price":"30010.00000","cost":"600.20000","fee":"0.00000","vol":"0.02000000","margin":"0.00000","misc":""}}}`,
+	}, func(path string, form url.Values) {
+		switch path {
+		case "/0/private/TradesHistory":
+			if form.Get("type") != TradeTypeNoPosition || form.Get("ofs") != "50" {
+				t.Errorf("Unexpected trades history options: %v", form)
+			}
+		case "/0/private/QueryTrades":
+			if form.Get("txid") != "THVRQM-33VKH-UCI7BS" || form.Get("trades") != "" {
+				t.Errorf("Unexpected query trades options: %v", form)
+			}
+		}
+	})
+	defer closeServer()
+
+	r, err := k.TradesHistory(context.Background(), &TradesHistoryOptions{Type: TradeTypeNoPosition, Offset: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != 2346 || len(r.Trades) != 2 {
+		t.Errorf("Expected 2 out of 2346 trades, got %d out of %d", len(r.Trades), r.Count)
+	}
+	trades := r.Trades.Sorted()
+	if trades[0].TxID != "THVRQM-33VKH-UCI7BS" || trades[1].TxID != "TCWJEG-FL4SZ-3FKGH6" {
+		t.Errorf("Trades should be sorted most recent first, got: %s, %s", trades[0].TxID, trades[1].TxID)
+	}
+	if !trades[0].Time.Equal(time.Unix(1616667796, 880200000)) {
+		t.Errorf("Unexpected trade time: %v", trades[0].Time)
+	}
+	if trades[0].Volume != "0.02000000" || trades[0].OrderTxID != "OQCLML-BW3P3-BUCMWZ" {
+		t.Errorf("Unexpected trade: %+v", trades[0])
+	}
+
+	q, err := k.QueryTrades(context.Background(), []string{"THVRQM-33VKH-UCI7BS"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*q)["THVRQM-33VKH-UCI7BS"].Cost != "600.20000" {
+		t.Errorf("Unexpected queried trades: %v", *q)
+	}
+}
+
+func Test_Kraken_OpenPositions(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/OpenPositions": `{"error":[],"result":{"TF5GVO-T7ZZ2-6NBKBI":{"ordertxid":"OLWNFG-LLH4R-D6SFFP",
+		"posstatus":"open","pair":"XXBTZUSD","time":1605280097.8294,"type":"buy","ordertype":"limit","cost":"104610.52842",
+		"fee":"289.06565","vol":"8.82412861","vol_closed":"0.20200000","margin":"20922.10568","value":"258797.5",
+		"net":"+154186.9728","terms":"0.0100% per 4 hours","rollovertm":"1616672637","misc":"","oflags":""}}}`,
+	}, func(path string, form url.Values) {
+		if form.Get("docalcs") != "true" {
+			t.Errorf("docalcs expected: true, got: %s", form.Get("docalcs"))
+		}
+	})
+	defer closeServer()
+
+	r, err := k.OpenPositions(context.Background(), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := (*r)["TF5GVO-T7ZZ2-6NBKBI"]
+	if !ok {
+		t.Fatalf("Open position is missing: %v", *r)
+	}
+	if p.TxID != "TF5GVO-T7ZZ2-6NBKBI" || p.Net != "+154186.9728" || p.Value != "258797.5" {
+		t.Errorf("Unexpected position: %+v", p)
+	}
+	if !p.RolloverTime.Equal(time.Unix(1616672637, 0)) {
+		t.Errorf("Unexpected rollover time: %v", p.RolloverTime)
+	}
+}
+
+// testLedgersPage returns a Ledgers result holding the entries [start, end)
+// of count entries, entry i being booked at 1610000000+i.
+func testLedgersPage(start, end, count int) string {
+	var entries []string
+	for i := start; i < end; i++ {
+		entries = append(entries, fmt.Sprintf(`"L%06d":{"refid":"R%06d","time":%d.1234,"type":"trade",
+		"subtype":"","aclass":"currency","asset":"ZEUR","amount":"-10.0000","fee":"0.0160","balance":"%d.0000"}`,
+			i, i, 1610000000+i, 1000+i))
+	}
+	return fmt.Sprintf(`{"error":[],"result":{"ledger":{%s},"count":%d}}`, strings.Join(entries, ","), count)
+}
+
+func Test_Kraken_WalkLedgers(t *testing.T) {
+	const count = 75
+	routes := map[string]string{
+		"/0/private/Ledgers": "",
+		"/0/private/QueryLedgers": `{"error":[],"result":{"L000003":{"refid":"R000003","time":1610000003.1234,
+		"type":"trade","subtype":"","aclass":"currency","asset":"ZEUR","amount":"-10.0000","fee":"0.0160","balance":"1003.0000"}}}`,
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		if path != "/0/private/Ledgers" {
+			if form.Get("id") != "L000003" {
+				t.Errorf("id expected: L000003, got: %s", form.Get("id"))
+			}
+			return
+		}
+		if form.Get("asset") != "ZEUR,XXBT" || form.Get("type") != LedgerTypeTrade {
+			t.Errorf("Unexpected ledgers options: %v", form)
+		}
+		ofs, _ := strconv.Atoi(form.Get("ofs"))
+		end := ofs + 50
+		if end > count {
+			end = count
+		}
+		routes[path] = testLedgersPage(count-end, count-ofs, count)
+	})
+	defer closeServer()
+
+	options := &LedgersOptions{Assets: []string{"ZEUR", "XXBT"}, Type: LedgerTypeTrade}
+	var ids []string
+	err := k.WalkLedgers(context.Background(), options, func(l LedgerEntry) error {
+		ids = append(ids, l.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != count || ids[0] != "L000074" || ids[count-1] != "L000000" {
+		t.Errorf("Expected %d entries, most recent first, got: %d", count, len(ids))
+	}
+	if options.Offset != 0 {
+		t.Error("WalkLedgers should not modify the options")
+	}
+
+	stop := errors.New("stop")
+	var n int
+	err = k.WalkLedgers(context.Background(), options, func(l LedgerEntry) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("The callback error should stop the walk, got: %v after %d entries", err, n)
+	}
+
+	r, err := k.QueryLedgers(context.Background(), []string{"L000003"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := (*r)["L000003"]
+	if l.ID != "L000003" || l.RefID != "R000003" || l.Balance != "1003.0000" || l.Asset != "ZEUR" {
+		t.Errorf("Unexpected ledger entry: %+v", l)
+	}
+	if !l.Time.Equal(time.Unix(1610000003, 123400000)) {
+		t.Errorf("Unexpected ledger time: %v", l.Time)
+	}
+}
+
+func Test_Kraken_TradeVolume(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/TradeVolume": `{"error":[],"result":{"currency":"ZUSD","volume":"200709587.4223",
+		"fees":{"XXBTZUSD":{"fee":"0.1000","minfee":"0.1000","maxfee":"0.2600","nextfee":null,"nextvolume":null,"tiervolume":"10000000.0000"}},
+		"fees_maker":{"XXBTZUSD":{"fee":"0.0000","minfee":"0.0000","maxfee":"0.1600","nextfee":null,"nextvolume":null,"tiervolume":"10000000.0000"}}}}`,
+	}, func(path string, form url.Values) {
+		if form.Get("pair") != XXBTZUSD || form.Get("fee-info") != "true" {
+			t.Errorf("Unexpected trade volume options: %v", form)
+		}
+	})
+	defer closeServer()
+
+	r, err := k.TradeVolume(context.Background(), []string{XXBTZUSD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Currency != "ZUSD" || r.Volume != "200709587.4223" {
+		t.Errorf("Unexpected trade volume: %s %s", r.Volume, r.Currency)
+	}
+	fee := r.Fees[XXBTZUSD]
+	if fee.Fee != "0.1000" || fee.MaxFee != "0.2600" || fee.NextFee != "" || fee.TierVolume != "10000000.0000" {
+		t.Errorf("Unexpected taker fee tier: %+v", fee)
+	}
+	if r.FeesMaker[XXBTZUSD].Fee != "0.0000" {
+		t.Errorf("Unexpected maker fee tier: %+v", r.FeesMaker[XXBTZUSD])
+	}
+}
+
+func Test_Kraken_Deposits(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/DepositMethods": `{"error":[],"result":[
+		{"method":"Bitcoin","limit":false,"fee":"0.0000000000","gen-address":true},
+		{"method":"Bitcoin Lightning","limit":"0.10000000","fee":"0.00000000","address-setup-fee":"0.00000000"}]}`,
+		"/0/private/DepositAddresses": `{"error":[],"result":[
+		{"address":"2N9fRkx5JTWXWHmXzZtvhQsufvoYRMq9ExV","expiretm":"0","new":true},
+		{"address":"rLHzPsX6oXkzU2qL12kHCH8G8cnZv1rBJh","expiretm":"1616672637","tag":"8327442"}]}`,
+		"/0/private/DepositStatus": `{"error":[],"result":[{"method":"Bitcoin","aclass":"currency","asset":"XXBT",
+		"refid":"QSKNDQ5-VCWMCO-ZTDGKG","txid":"6544b41b607d8b2512baf801755a3a87b6890eacdb451be8a94059fb11f0a8d9",
+		"info":"2Myd4eaAW96ojk38A2uDK4FbioCayvkEgVq","amount":"0.78125000","fee":"0.0000000000","time":1546992722,
+		"status":"Success"}]}`,
+	}, func(path string, form url.Values) {
+		if form.Get("asset") != "XXBT" {
+			t.Errorf("asset expected: XXBT, got: %s", form.Get("asset"))
+		}
+		if path == "/0/private/DepositAddresses" && (form.Get("method") != "Bitcoin" || form.Get("new") != "true") {
+			t.Errorf("Unexpected deposit addresses options: %v", form)
+		}
+	})
+	defer closeServer()
+
+	methods, err := k.DepositMethods(context.Background(), "XXBT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) != 2 || methods[0].Limit != "" || !methods[0].GenAddress || methods[1].Limit != "0.10000000" {
+		t.Errorf("Unexpected deposit methods: %+v", methods)
+	}
+
+	addresses, err := k.DepositAddresses(context.Background(), "XXBT", "Bitcoin", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 || !addresses[0].New || !addresses[0].ExpireTime.IsZero() {
+		t.Errorf("Unexpected deposit addresses: %+v", addresses)
+	}
+	if addresses[1].Tag != "8327442" || !addresses[1].ExpireTime.Equal(time.Unix(1616672637, 0)) {
+		t.Errorf("Unexpected deposit address: %+v", addresses[1])
+	}
+
+	status, err := k.DepositStatus(context.Background(), "XXBT", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].Status != "Success" || status[0].Amount != "0.78125000" ||
+		!status[0].Time.Equal(time.Unix(1546992722, 0)) {
+		t.Errorf("Unexpected deposit status: %+v", status)
+	}
+}
+
+func Test_Kraken_Withdraw(t *testing.T) {
+	var calls []string
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/WithdrawInfo": `{"error":[],"result":{"method":"Bitcoin","limit":"332.00956139",
+		"amount":"0.72485000","fee":"0.00015000"}}`,
+		"/0/private/Withdraw":       `{"error":[],"result":{"refid":"AGBSO6T-UFMTTQ-I7KGS6"}}`,
+		"/0/private/WithdrawCancel": `{"error":[],"result":true}`,
+		"/0/private/WithdrawStatus": `{"error":[],"result":[{"method":"Bitcoin","aclass":"currency","asset":"XXBT",
+		"refid":"AGBZNBO-5P2XSB-RFVF6J","txid":"THVRQM-33VKH-UCI7BS","info":"mzp6yUVMRxfasyfwzTZjjy38dHqMX7Z3GR",
+		"amount":"0.72485000","fee":"0.00015000","time":1617014586,"status":"Pending","status-prop":"cancel-pending"}]}`,
+	}, func(path string, form url.Values) {
+		calls = append(calls, path)
+		if form.Get("asset") != "XXBT" {
+			t.Errorf("asset expected: XXBT, got: %s", form.Get("asset"))
+		}
+	})
+	defer closeServer()
+
+	info, err := k.WithdrawInfo(context.Background(), "XXBT", "cold storage", "0.725")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Method != "Bitcoin" || info.Fee != "0.00015000" || info.Limit != "332.00956139" {
+		t.Errorf("Unexpected withdraw info: %+v", *info)
+	}
+
+	request := &WithdrawRequest{Asset: "XXBT", Key: "cold storage", Amount: "0.725"}
+	if _, err := k.Withdraw(context.Background(), request); err != ErrWithdrawNotConfirmed {
+		t.Errorf("An unconfirmed withdrawal should fail with ErrWithdrawNotConfirmed, got: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("An unconfirmed withdrawal should not be sent, got calls: %v", calls)
+	}
+	request.Confirmed = true
+	refid, err := k.Withdraw(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refid != "AGBSO6T-UFMTTQ-I7KGS6" {
+		t.Errorf("refid expected: AGBSO6T-UFMTTQ-I7KGS6, got: %s", refid)
+	}
+
+	status, err := k.WithdrawStatus(context.Background(), "XXBT", "Bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].StatusProp != "cancel-pending" || status[0].RefID != "AGBZNBO-5P2XSB-RFVF6J" {
+		t.Errorf("Unexpected withdraw status: %+v", status)
+	}
+
+	ok, err := k.WithdrawCancel(context.Background(), "XXBT", "AGBZNBO-5P2XSB-RFVF6J")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("WithdrawCancel should report the cancellation")
+	}
+}
+
+func Test_Kraken_Staking(t *testing.T) {
+	const transactions = `{"error":[],"result":[{"method":"polkadot-staked","aclass":"currency","asset":"DOT.S",
+	"refid":"RUSB7W6-ESIXUX-K6PVTM","amount":"0.0300000000","fee":"0.0000000000","time":1622967367,
+	"status":"Initial","type":"bonding","bond_start":1622971496,"bond_end":1622971496}]}`
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/Staking/Assets": `{"error":[],"result":[{"method":"polkadot-staked","asset":"DOT",
+		"staking_asset":"DOT.S","rewards":{"reward":"12.00","type":"percentage"},"on_chain":true,"can_stake":true,
+		"can_unstake":true,"minimum_amount":{"staking":"0.0000000000","unstaking":"0.0000000000"}}]}`,
+		"/0/private/Stake":                `{"error":[],"result":{"refid":"BOG5AE5-KSCNR4-VPNPEV"}}`,
+		"/0/private/Unstake":              `{"error":[],"result":{"refid":"BOG5AE5-KSCNR4-VPNPEW"}}`,
+		"/0/private/Staking/Pending":      transactions,
+		"/0/private/Staking/Transactions": transactions,
+	}, func(path string, form url.Values) {
+		if path == "/0/private/Stake" && (form.Get("asset") != "DOT" || form.Get("method") != "polkadot-staked") {
+			t.Errorf("Unexpected stake parameters: %v", form)
+		}
+		if path == "/0/private/Unstake" && (form.Get("asset") != "DOT.S" || form.Get("amount") != "0.03") {
+			t.Errorf("Unexpected unstake parameters: %v", form)
+		}
+	})
+	defer closeServer()
+
+	assets, err := k.StakingAssets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].StakingAsset != "DOT.S" || assets[0].Rewards.Reward != "12.00" || !assets[0].CanStake {
+		t.Errorf("Unexpected staking assets: %+v", assets)
+	}
+
+	refid, err := k.Stake(context.Background(), "DOT", "0.03", assets[0].Method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refid != "BOG5AE5-KSCNR4-VPNPEV" {
+		t.Errorf("Unexpected stake refid: %s", refid)
+	}
+	if refid, err = k.Unstake(context.Background(), "DOT.S", "0.03"); err != nil {
+		t.Fatal(err)
+	}
+	if refid != "BOG5AE5-KSCNR4-VPNPEW" {
+		t.Errorf("Unexpected unstake refid: %s", refid)
+	}
+
+	pending, err := k.StakingPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Type != "bonding" || !pending[0].BondEnd.Equal(time.Unix(1622971496, 0)) {
+		t.Errorf("Unexpected pending staking transactions: %+v", pending)
+	}
+	if _, err := k.StakingTransactions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Kraken_EditOrder(t *testing.T) {
+	var calls int
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/public/AssetPairs": publicRoutes["/0/public/AssetPairs"],
+		"/0/private/EditOrder": `{"error":[],"result":{"status":"ok","txid":"OFVXHJ-KPQ3B-VS7ELA",
+		"originaltxid":"OHYO67-6LP66-HMQ437","volume":"0.00030000","price":"19500.0","price2":"32500.0",
+		"orders_cancelled":1,"descr":{"order":"buy 0.00030000 XXBTZGBP @ limit 19500.0"}}}`,
+	}, func(path string, form url.Values) {
+		calls++
+		if form.Get("txid") != "OHYO67-6LP66-HMQ437" || form.Get("price") != "19500.0" ||
+			form.Get("oflags") != "post" || form.Get("volume") != "" {
+			t.Errorf("Unexpected edit parameters: %v", form)
+		}
+	})
+	defer closeServer()
+
+	edit := &EditOrderRequest{TxID: "OHYO67-6LP66-HMQ437", Pair: XXBTZEUR, Price: "19500.0", OFlags: []string{OrderFlagPost}}
+	r, err := k.EditOrder(context.Background(), edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TxID != "OFVXHJ-KPQ3B-VS7ELA" || r.OriginalTxID != "OHYO67-6LP66-HMQ437" || r.OrdersCancelled != 1 {
+		t.Errorf("Unexpected edit result: %+v", *r)
+	}
+
+	edit.Volume = "0.000300001"
+	if _, err := k.EditOrder(context.Background(), edit); err == nil {
+		t.Error("EditOrder should reject a volume with too many decimals")
+	}
+	if calls != 1 {
+		t.Errorf("Invalid edits should not be sent, got %d calls", calls)
+	}
+}
+
+func Test_Kraken_AddOrderBatch(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/public/AssetPairs": publicRoutes["/0/public/AssetPairs"],
+		"/0/private/AddOrderBatch": `{"error":[],"result":{"orders":[
+		{"txid":"O5OR23-ZLHGZ-SWZQPG","descr":{"order":"buy 1.02010000 XBTEUR @ limit 29000.0"}},
+		{"error":"EOrder:Insufficient funds"}]}}`,
+		"/0/private/CancelOrderBatch": `{"error":[],"result":{"count":2}}`,
+	}, func(path string, form url.Values) {
+		var expected url.Values
+		switch path {
+		case "/0/private/AddOrderBatch":
+			expected = url.Values{
+				"pair":                        {"XBTEUR"},
+				"orders[0][type]":             {"buy"},
+				"orders[0][ordertype]":        {"limit"},
+				"orders[0][price]":            {"29000.0"},
+				"orders[0][volume]":           {"1.0201"},
+				"orders[0][close][ordertype]": {"limit"},
+				"orders[0][close][price]":     {"31000.0"},
+				"orders[1][type]":             {"sell"},
+				"orders[1][price]":            {"45000.5"},
+				"orders[1][oflags]":           {"post"},
+				"deadline":                    {"2022-12-25T09:08:59Z"},
+				"orders[1][close][ordertype]": {""},
+				"orders[0][pair]":             {""},
+				"orders[0][validate]":         {""},
+			}
+		case "/0/private/CancelOrderBatch":
+			expected = url.Values{"orders[0]": {"O5OR23-ZLHGZ-SWZQPG"}, "orders[1]": {"42"}}
+		}
+		for key, v := range expected {
+			if form.Get(key) != v[0] {
+				t.Errorf("Form value %s expected: %s, got: %s", key, v[0], form.Get(key))
+			}
+		}
+	})
+	defer closeServer()
+
+	orders := []AddOrderRequest{
+		{Type: OrderBuy, OrderType: OrderTypeLimit, Price: "29000.0", Volume: "1.0201",
+			Close: &CloseOrder{OrderType: OrderTypeLimit, Price: "31000.0"}},
+		{Pair: "XBTEUR", Type: OrderSell, OrderType: OrderTypeLimit, Price: "45000.5", Volume: "1", OFlags: []string{OrderFlagPost}},
+	}
+	r, err := k.AddOrderBatch(context.Background(), "XBTEUR", orders, "2022-12-25T09:08:59Z", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Orders) != 2 || r.Orders[0].TxID != "O5OR23-ZLHGZ-SWZQPG" || r.Orders[1].Error != "EOrder:Insufficient funds" {
+		t.Errorf("Unexpected batch result: %+v", r.Orders)
+	}
+	if orders[0].Pair != "" {
+		t.Error("AddOrderBatch should not modify the orders")
+	}
+
+	if _, err := k.AddOrderBatch(context.Background(), "XBTEUR", orders[:1], "", false); err == nil {
+		t.Error("AddOrderBatch should reject a batch of a single order")
+	}
+	orders[1].Pair = "ETHXBT"
+	if _, err := k.AddOrderBatch(context.Background(), "XBTEUR", orders, "", false); err == nil {
+		t.Error("AddOrderBatch should reject orders on another pair")
+	}
+
+	c, err := k.CancelOrderBatch(context.Background(), []string{"O5OR23-ZLHGZ-SWZQPG", "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Count != 2 {
+		t.Errorf("Cancelled count expected: 2, got: %d", c.Count)
+	}
+}
+
+func Test_Kraken_GetWebSocketsToken(t *testing.T) {
+	var calls int
+	routes := map[string]string{
+		"/0/private/GetWebSocketsToken": `{"error":[],"result":{"token":"1Dwc4lzSwNWOAwkMdqhssNNFhs1ed606d1WcF3XfEMw","expires":900}}`,
+	}
+	k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+		calls++
+	})
+	defer closeServer()
+
+	for i := 0; i < 2; i++ {
+		token, err := k.GetWebSocketsToken(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Token != "1Dwc4lzSwNWOAwkMdqhssNNFhs1ed606d1WcF3XfEMw" {
+			t.Errorf("Unexpected token: %s", token.Token)
+		}
+		if d := token.ExpireTime.Sub(time.Now()); d < 14*time.Minute || d > 15*time.Minute {
+			t.Errorf("Token should expire in 15 minutes, got: %v", d)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("The token should be cached, got %d calls", calls)
+	}
+
+	// a token about to expire is replaced
+	routes["/0/private/GetWebSocketsToken"] = `{"error":[],"result":{"token":"second","expires":30}}`
+	k.wsToken.token.ExpireTime = time.Now().Add(WebSocketsTokenRefreshMargin / 2)
+	token, err := k.GetWebSocketsToken(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token != "second" || calls != 2 {
+		t.Errorf("A token about to expire should be replaced, got: %s after %d calls", token.Token, calls)
+	}
+}
+
+func Test_Kraken_GetWebSocketsToken_Concurrent(t *testing.T) {
+	var calls int32
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/GetWebSocketsToken": `{"error":[],"result":{"token":"shared","expires":900}}`,
+	}, func(path string, form url.Values) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(requested)
+		}
+		<-release
+	})
+	defer closeServer()
+
+	tokens := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			token, err := k.GetWebSocketsToken(context.Background())
+			if err != nil {
+				t.Error(err)
+				tokens <- ""
+				return
+			}
+			tokens <- token.Token
+		}()
+	}
+	<-requested
+
+	// a call waiting for the token can be cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := k.GetWebSocketsToken(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if token := <-tokens; token != "shared" {
+			t.Errorf("Unexpected token: %q", token)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("The concurrent calls should share the request, got %d calls", n)
+	}
+}
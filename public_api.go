@@ -1,10 +1,12 @@
 package kraken
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 // GetServerTime returns server time.
@@ -12,51 +14,33 @@ import (
 //
 // https://www.kraken.com/help/api#get-server-time
 func (k *Kraken) GetServerTime() (*ServerTime, error) {
+	return k.GetServerTimeCtx(context.Background())
+}
 
-	req, err := http.NewRequest("GET", urlGetServerTime, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	var dat ServerTimeResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+// GetServerTimeCtx is GetServerTime with a context controlling the call and its retries.
+func (k *Kraken) GetServerTimeCtx(ctx context.Context) (*ServerTime, error) {
+	var dat ServerTime
+	if err := k.queryPublic(ctx, endpointGetServerTime, nil, &dat); err != nil {
 		return nil, err
 	}
 
-	return &dat.Result, nil
+	return &dat, nil
 }
 
 // GetAssetsInfo returns assets info.
 // https://www.kraken.com/help/api#get-asset-info
 func (k *Kraken) GetAssetsInfo() (*AssetsInfoMap, error) {
+	return k.GetAssetsInfoCtx(context.Background())
+}
 
-	req, err := http.NewRequest("GET", urlGetAssetsInfo, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	var dat AssetsInfoResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+// GetAssetsInfoCtx is GetAssetsInfo with a context controlling the call and its retries.
+func (k *Kraken) GetAssetsInfoCtx(ctx context.Context) (*AssetsInfoMap, error) {
+	var dat AssetsInfoMap
+	if err := k.queryPublic(ctx, endpointGetAssetsInfo, nil, &dat); err != nil {
 		return nil, err
 	}
 
-	if len(dat.Error) > 0 {
-		return nil, errors.New("JSON Error: " + dat.Error[0])
-	}
-
-	return &dat.Result, nil
+	return &dat, nil
 }
 
 // GetTradablePairs returns all tradable pairs from the api.
@@ -67,28 +51,17 @@ func (k *Kraken) GetAssetsInfo() (*AssetsInfoMap, error) {
 //
 // https://www.kraken.com/help/api#get-tradable-pairs
 func (k *Kraken) GetTradablePairs() (*AssetPairMap, error) {
+	return k.GetTradablePairsCtx(context.Background())
+}
 
-	req, err := http.NewRequest("GET", urlGetTradablePairs, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	var dat AssetPairResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+// GetTradablePairsCtx is GetTradablePairs with a context controlling the call and its retries.
+func (k *Kraken) GetTradablePairsCtx(ctx context.Context) (*AssetPairMap, error) {
+	var dat AssetPairMap
+	if err := k.queryPublic(ctx, endpointGetTradablePairs, nil, &dat); err != nil {
 		return nil, err
 	}
 
-	if len(dat.Error) > 0 {
-		return nil, errors.New("JSON Error: " + dat.Error[0])
-	}
-
-	return &dat.Result, nil
+	return &dat, nil
 }
 
 // GetTickerInfo return ticker info.
@@ -98,41 +71,24 @@ func (k *Kraken) GetTradablePairs() (*AssetPairMap, error) {
 //
 // https://www.kraken.com/help/api#get-ticker-info
 func (k *Kraken) GetTickerInfo(pairs []string) (*TickerInfoMap, error) {
+	return k.GetTickerInfoCtx(context.Background(), pairs)
+}
+
+// GetTickerInfoCtx is GetTickerInfo with a context controlling the call and its retries.
+func (k *Kraken) GetTickerInfoCtx(ctx context.Context, pairs []string) (*TickerInfoMap, error) {
 
 	if len(pairs) == 0 {
 		return nil, errors.New("JSON Error: Parameter pairs cannot be empty")
 	}
-	var pairList string
-	pairList = pairs[0]
-	for i := 1; i < len(pairs); i++ {
-		pairList += "," + pairs[i]
-	}
+	query := url.Values{}
+	query.Add("pair", strings.Join(pairs, ","))
 
-	req, err := http.NewRequest("GET", urlGetTickerInfo, nil)
-	if err != nil {
+	var dat TickerInfoMap
+	if err := k.queryPublic(ctx, endpointGetTickerInfo, query, &dat); err != nil {
 		return nil, err
 	}
-	query := req.URL.Query()
-	query.Add("pair", pairList)
-	req.URL.RawQuery = query.Encode()
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
 
-	var dat TickerInfoResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
-		return nil, err
-	}
-
-	if len(dat.Error) > 0 {
-		return nil, errors.New("JSON Error: " + dat.Error[0])
-	}
-
-	return &dat.Result, nil
+	return &dat, nil
 }
 
 // GetOHLCData returns the OHLC data record for a given currency pair.
@@ -152,56 +108,33 @@ func (k *Kraken) GetTickerInfo(pairs []string) (*TickerInfoMap, error) {
 //
 // https://www.kraken.com/help/api#get-ohlc-data
 func (k *Kraken) GetOHLCData(options *OHLCQueryOptions) (*OHLCEntryData, error) {
+	return k.GetOHLCDataCtx(context.Background(), options)
+}
+
+// GetOHLCDataCtx is GetOHLCData with a context controlling the call and its retries.
+func (k *Kraken) GetOHLCDataCtx(ctx context.Context, options *OHLCQueryOptions) (*OHLCEntryData, error) {
 
 	if len(options.Pair) == 0 {
 		return nil, errors.New("JSON Error: Parameter pair cannot be empty")
 	}
 
-	req, err := http.NewRequest("GET", urlGetOHLCData, nil)
-	if err != nil {
-		return nil, err
-	}
-	query := req.URL.Query()
+	query := url.Values{}
 	query.Add("pair", options.Pair)
 	query.Add("interval", strconv.Itoa(options.Interval))
 	if options.Since != "" {
 		query.Add("since", options.Since)
 	}
-	req.URL.RawQuery = query.Encode()
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
 
 	// The OHLC data is not of uniform type, and needs to be processed manually
 	// The format is a map of PAIR into the array of OHLCEntries, followed by "last": timestamp
 
-	var dat map[string]json.RawMessage
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+	var ohlcDataMap map[string]json.RawMessage
+	if err := k.queryPublic(ctx, endpointGetOHLCData, query, &ohlcDataMap); err != nil {
 		return nil, err
 	}
-	// check the error
-	if errStringRaw, ok := dat["Error"]; ok {
-		var errString []string
-		if err := json.Unmarshal(errStringRaw, &errString); err != nil {
-			return nil, err
-		}
-		if len(errString) > 0 {
-			return nil, errors.New("JSON Error: " + errString[0])
-		}
-	}
-	// parse the actual OHLC data entries
-	ohlcDataMapRaw, ok := dat["result"]
-	if !ok {
+	if ohlcDataMap == nil {
 		return nil, errors.New("JSON Error: OHLC data result not present")
 	}
-	var ohlcDataMap map[string]json.RawMessage
-	if err := json.Unmarshal(ohlcDataMapRaw, &ohlcDataMap); err != nil {
-		return nil, err
-	}
 	ohlcData := &OHLCEntryData{}
 	ohlcData.Pair = options.Pair
 	tmpTimestamp, ok := ohlcDataMap["last"]
@@ -234,41 +167,30 @@ func (k *Kraken) GetOHLCData(options *OHLCQueryOptions) (*OHLCEntryData, error)
 //
 // https://www.kraken.com/help/api#get-order-book
 func (k *Kraken) GetOrderBook(pair string, count int) (*OrderBookMap, error) {
+	return k.GetOrderBookCtx(context.Background(), pair, count)
+}
+
+// GetOrderBookCtx is GetOrderBook with a context controlling the call and its retries.
+func (k *Kraken) GetOrderBookCtx(ctx context.Context, pair string, count int) (*OrderBookMap, error) {
 
 	if len(pair) == 0 {
 		return nil, errors.New("JSON Error: Parameter pair cannot be empty")
 	}
 
-	req, err := http.NewRequest("GET", urlGetOrderBook, nil)
-	if err != nil {
-		return nil, err
-	}
-	query := req.URL.Query()
+	query := url.Values{}
 	query.Add("pair", pair)
 	query.Add("count", strconv.Itoa(count))
-	req.URL.RawQuery = query.Encode()
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
 
-	var dat OrderBookResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+	var dat OrderBookMap
+	if err := k.queryPublic(ctx, endpointGetOrderBook, query, &dat); err != nil {
 		return nil, err
 	}
 
-	if len(dat.Error) > 0 {
-		return nil, errors.New("JSON Error: " + dat.Error[0])
-	}
-
-	tmp := dat.Result[pair]
+	tmp := dat[pair]
 	tmp.Pair = pair
-	dat.Result[pair] = tmp
+	dat[pair] = tmp
 
-	return &dat.Result, nil
+	return &dat, nil
 }
 
 // GetTrades returns the recent trades data
@@ -283,39 +205,28 @@ func (k *Kraken) GetOrderBook(pair string, count int) (*OrderBookMap, error) {
 //
 // https://www.kraken.com/help/api#get-recent-trades
 func (k *Kraken) GetTrades(pair string, since string) (*TradeBook, error) {
+	return k.GetTradesCtx(context.Background(), pair, since)
+}
+
+// GetTradesCtx is GetTrades with a context controlling the call and its retries.
+func (k *Kraken) GetTradesCtx(ctx context.Context, pair string, since string) (*TradeBook, error) {
 
 	if len(pair) == 0 {
 		return nil, errors.New("JSON Error: Parameter pair cannot be empty")
 	}
 
-	req, err := http.NewRequest("GET", urlGetTrades, nil)
-	if err != nil {
-		return nil, err
-	}
-	query := req.URL.Query()
+	query := url.Values{}
 	query.Add("pair", pair)
 	if since != "" {
 		query.Add("since", since)
 	}
-	req.URL.RawQuery = query.Encode()
-
-	resp, err := k.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
 
-	var dat TradesResult
-	if err := json.NewDecoder(resp.Body).Decode(&dat); err != nil {
+	var dat TradeBook
+	if err := k.queryPublic(ctx, endpointGetTrades, query, &dat); err != nil {
 		return nil, err
 	}
 
-	if len(dat.Error) > 0 {
-		return nil, errors.New("JSON Error: " + dat.Error[0])
-	}
-
-	dat.Result.Pair = pair
+	dat.Pair = pair
 
-	return &dat.Result, nil
+	return &dat, nil
 }
@@ -1,14 +1,46 @@
 package kraken
 
 import (
-	"encoding/json"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
 
+// Canned responses of the public endpoints, as served by newTestServer.
+var publicRoutes = map[string]string{
+	"/0/public/Time": `{"error":[],"result":{"unixtime":1493926890,"rfc1123":"Thu,  4 May 17 19:41:30 +0000"}}`,
+	"/0/public/Assets": `{"error":[],"result":{
+		"XXBT":{"aclass":"currency","altname":"XBT","decimals":10,"display_decimals":5},
+		"XETH":{"aclass":"currency","altname":"ETH","decimals":10,"display_decimals":5},
+		"ZEUR":{"aclass":"currency","altname":"EUR","decimals":4,"display_decimals":2},
+		"XETC":{"aclass":"currency","altname":"ETC","decimals":10,"display_decimals":5},
+		"ZUSD":{"aclass":"currency","altname":"USD","decimals":4,"display_decimals":2}}}`,
+	"/0/public/AssetPairs": `{"error":[],"result":{
+		"XETHXXBT":{"altname":"ETHXBT","wsname":"ETH/XBT","aclass_base":"currency","base":"XETH","aclass_quote":"currency","quote":"XXBT","lot":"unit","pair_decimals":5,"lot_decimals":8,"lot_multiplier":1,"leverage_buy":[2,3,4,5],"leverage_sell":[2,3,4,5],"fees":[[0,0.26],[50000,0.24]],"fees_maker":[[0,0.16],[50000,0.14]],"fee_volume_currency":"ZUSD","margin_call":80,"margin_stop":40},
+		"XETCZEUR":{"altname":"ETCEUR","wsname":"ETC/EUR","aclass_base":"currency","base":"XETC","aclass_quote":"currency","quote":"ZEUR","lot":"unit","pair_decimals":3,"lot_decimals":8,"lot_multiplier":1,"leverage_buy":[],"leverage_sell":[],"fees":[[0,0.26]],"fees_maker":[[0,0.16]],"fee_volume_currency":"ZUSD","margin_call":80,"margin_stop":40},
+		"XETCZUSD":{"altname":"ETCUSD","wsname":"ETC/USD","aclass_base":"currency","base":"XETC","aclass_quote":"currency","quote":"ZUSD","lot":"unit","pair_decimals":3,"lot_decimals":8,"lot_multiplier":1,"leverage_buy":[],"leverage_sell":[],"fees":[[0,0.26]],"fees_maker":[[0,0.16]],"fee_volume_currency":"ZUSD","margin_call":80,"margin_stop":40},
+		"XXBTZEUR":{"altname":"XBTEUR","wsname":"XBT/EUR","aclass_base":"currency","base":"XXBT","aclass_quote":"currency","quote":"ZEUR","lot":"unit","pair_decimals":1,"lot_decimals":8,"lot_multiplier":1,"leverage_buy":[2,3,4,5],"leverage_sell":[2,3,4,5],"fees":[[0,0.26],[50000,0.24]],"fees_maker":[[0,0.16],[50000,0.14]],"fee_volume_currency":"ZUSD","margin_call":80,"margin_stop":40},
+		"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","aclass_base":"currency","base":"XXBT","aclass_quote":"currency","quote":"ZUSD","lot":"unit","pair_decimals":1,"lot_decimals":8,"lot_multiplier":1,"leverage_buy":[2,3,4,5],"leverage_sell":[2,3,4,5],"fees":[[0,0.26],[50000,0.24]],"fees_maker":[[0,0.16],[50000,0.14]],"fee_volume_currency":"ZUSD","margin_call":80,"margin_stop":40}}}`,
+	"/0/public/Ticker": `{"error":[],"result":{
+		"XETHXXBT":{"a":["0.94100","1","1.000"],"b":["0.93961","24","24.000"],"c":["0.94100","0.20000000"],"v":["5279.58","14960.68"],"p":["0.93561","0.93091"],"t":[1119,3212],"l":["0.92401","0.91313"],"h":["0.94900","0.94900"],"o":"0.93001"},
+		"XXBTZEUR":{"a":["1425.26000","1","1.000"],"b":["1425.00000","3","3.000"],"c":["1425.26000","8.96796823"],"v":["2634.96","7146.22"],"p":["1418.63","1409.43"],"t":[4911,12934],"l":["1401.00000","1393.00000"],"h":["1430.00000","1430.00000"],"o":"1407.98100"}}}`,
+	"/0/public/OHLC": `{"error":[],"result":{
+		"XETHXXBT":[[1493786400,"0.93001","0.93100","0.92900","0.93050","0.93010","12.50000000",5],[1493786460,"0.93050","0.93050","0.93000","0.93000","0.93020","3.10000000",2]],
+		"XXBTZEUR":[[1493786400,"1326.800","1326.860","1326.500","1326.860","1326.700","1.20000000",3],[1493786460,"1326.860","1326.880","1324.533","1326.880","1326.643","3.93936569",9]],
+		"last":1493829600}}`,
+	"/0/public/Depth": `{"error":[],"result":{
+		"XETHXXBT":{"asks":[["0.94100","1.000",1493926357],["0.94200","2.000",1493926350]],"bids":[["0.93961","24.000",1493926357]]},
+		"XXBTZEUR":{"asks":[["1425.26000","1.000",1493926357],["1425.50000","0.500",1493926351]],"bids":[["1425.00000","3.000",1493926357]]}}}`,
+	"/0/public/Trades": `{"error":[],"result":{"XXBTZEUR":[["1425.26000","8.96796823",1493926357.0243,"s","m",""],
+	["1425.01000","0.01000000",1493926357.0391,"s","m",""],["1425.00000","0.10000000",1493926357.0579,"s","m",
+	""],["1425.00000","0.10000000",1493926357.0624,"s","m",""]], "last":"1493926890306801911"}}`,
+}
+
 func Test_Kraken_GetServerTime(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	r, err := k.GetServerTime()
 	if err != nil {
@@ -25,8 +57,8 @@ func Test_Kraken_GetServerTime(t *testing.T) {
 }
 
 func Test_Kraken_GetAssetsInfo(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	r, err := k.GetAssetsInfo()
 	if err != nil {
@@ -47,8 +79,8 @@ func Test_Kraken_GetAssetsInfo(t *testing.T) {
 }
 
 func Test_Kraken_GetTradablePairs(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	r, err := k.GetTradablePairs()
 	if err != nil {
@@ -73,8 +105,8 @@ func Test_Kraken_GetTradablePairs(t *testing.T) {
 }
 
 func Test_Kraken_GetTickerInfo(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	testCases := []string{XETHXXBT, XXBTZEUR}
 	r, err := k.GetTickerInfo(testCases)
@@ -92,11 +124,14 @@ func Test_Kraken_GetTickerInfo(t *testing.T) {
 	if _, ok := (*r)[testCases[1]]; !ok {
 		t.Errorf("%s return TickerInfo is missing", testCases[1])
 	}
+	if ticker := (*r)[XXBTZEUR]; ticker.Open.String() != "1407.98100" || ticker.Ask.Price.String() != "1425.26000" {
+		t.Errorf("Unexpected ticker prices: %v %v", ticker.Open, ticker.Ask)
+	}
 }
 
 func Test_Kraken_GetOHLCData(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	testCases := []string{"XETHXXBT", "XXBTZEUR"}
 	for _, v := range testCases {
@@ -115,13 +150,15 @@ func Test_Kraken_GetOHLCData(t *testing.T) {
 		}
 		if len(r.Data) < 2 {
 			t.Errorf("OHLC data for %s is missing", r.Pair)
+		} else if r.Data[0].Open.IsZero() || r.Data[0].Close.IsZero() || r.Data[0].Volume.IsZero() {
+			t.Errorf("OHLC columns for %s are missing: %+v", r.Pair, r.Data[0])
 		}
 	}
 }
 
 func Test_Kraken_GetOrderBook(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
 	testCases := []string{"XETHXXBT", "XXBTZEUR"}
 	for _, v := range testCases {
@@ -144,34 +181,49 @@ func Test_Kraken_GetOrderBook(t *testing.T) {
 }
 
 func Test_Kraken_GetTrades(t *testing.T) {
-	var k Kraken
-	k.Init()
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
 
-	const testCase = `{"error":[],"result":{"XXBTZEUR":[["1425.26000","8.96796823",1493926357.0243,"s","m",""],
-	["1425.01000","0.01000000",1493926357.0391,"s","m",""],["1425.00000","0.10000000",1493926357.0579,"s","m",
-	""],["1425.00000","0.10000000",1493926357.0624,"s","m",""]], "last":"1493926890306801911"}}`
-
-	var trRaw TradesResult
-	err := json.Unmarshal([]byte(testCase), &trRaw)
+	tr, err := k.GetTrades(XXBTZEUR, "")
 	if err != nil {
 		t.Error(err)
-		t.Fail()
+		t.FailNow()
 	}
 
-	tr := trRaw.Result
 	if tr.Pair != "XXBTZEUR" {
 		t.Errorf("Expected Pair should be XXBTZEUR, but got: %s", tr.Pair)
 	}
 	if tr.Last != "1493926890306801911" {
 		t.Errorf("Expected Last should be 1493926890306801911, but got: %s", tr.Last)
 	}
-	if tr.Data[0].Volume != "8.96796823" {
+	if tr.Data[0].Volume.String() != "8.96796823" {
 		t.Errorf("Expected volume for first entry 8.96796823, but got: %s", tr.Data[0].Volume)
 	}
-	if tr.Data[3].Price != "1425.00000" {
+	if tr.Data[3].Price.String() != "1425.00000" {
 		t.Errorf("Expected price for third entry 1425.00000, but got: %s", tr.Data[3].Price)
 	}
-	if tr.Data[3].Volume != "0.10000000" {
+	if tr.Data[3].Volume.String() != "0.10000000" {
 		t.Errorf("Expected volume for first entry 0.10000000, but got: %s", tr.Data[3].Volume)
 	}
 }
+
+func Test_Kraken_GetTickerInfoCtx_Canceled(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	policy := &testRetryPolicy{}
+	k := NewClient(WithBaseURL(srv.URL), WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := k.GetTickerInfoCtx(ctx, []string{XXBTZEUR}); err == nil {
+		t.Error("GetTickerInfoCtx should fail once the context is done")
+	}
+	if policy.attempts != 0 {
+		t.Errorf("A call with a done context should not be retried, got %d retries", policy.attempts)
+	}
+}
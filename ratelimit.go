@@ -0,0 +1,178 @@
+package kraken
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tier describes the call counter of a verification tier: each private call
+// increases the counter by its cost, the counter decays over time and calls
+// are rejected once it would exceed its maximum.
+//
+// https://docs.kraken.com/rest/#section/Rate-Limits
+type Tier struct {
+	// MaxCounter is the maximum value of the counter, which must be positive.
+	MaxCounter float64
+	// DecayRate is the decrease of the counter per second, which must be
+	// positive.
+	DecayRate float64
+}
+
+/* Call counters of the verification tiers. */
+var (
+	TierStarter      = Tier{MaxCounter: 15, DecayRate: 0.33}
+	TierIntermediate = Tier{MaxCounter: 20, DecayRate: 0.5}
+	TierPro          = Tier{MaxCounter: 20, DecayRate: 1}
+)
+
+// DefaultEndpointCosts gives the increase of the call counter of the endpoints
+// whose cost is not 1. The public endpoints are limited per IP address and
+// the order placement ones by the matching engine, so they do not count.
+var DefaultEndpointCosts = map[string]float64{
+	endpointGetServerTime:    0,
+	endpointGetAssetsInfo:    0,
+	endpointGetTradablePairs: 0,
+	endpointGetTickerInfo:    0,
+	endpointGetOHLCData:      0,
+	endpointGetOrderBook:     0,
+	endpointGetTrades:        0,
+
+	endpointAddOrder:    0,
+	endpointCancelOrder: 0,
+	endpointCancelAll:   0,
+	endpointCancelAfter: 0,
+	endpointEditOrder:   0,
+	endpointAddBatch:    0,
+	endpointCancelBatch: 0,
+
+	endpointTrades:       2,
+	endpointQueryTrades:  2,
+	endpointLedgers:      2,
+	endpointQueryLedgers: 2,
+}
+
+// CallCounterState is a snapshot of the call counter of a CallCounterLimiter.
+type CallCounterState struct {
+	// Counter is the current value of the counter.
+	Counter float64
+	// MaxCounter is the maximum value of the counter.
+	MaxCounter float64
+	// DecayRate is the decrease of the counter per second.
+	DecayRate float64
+}
+
+// CallCounterLimiter is a RateLimiter modelling the call counter of an API key:
+// Wait blocks until the cost of the call fits below the maximum of the tier.
+// It is safe for concurrent use, and is to be shared by the clients using
+// the same key.
+type CallCounterLimiter struct {
+	mu      sync.Mutex
+	tier    Tier
+	costs   map[string]float64
+	counter float64
+	updated time.Time
+	// now and sleep give and wait for the time, replaced by the tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewCallCounterLimiter returns a limiter for the call counter of the given
+// tier, using DefaultEndpointCosts. The maximum and the decay rate of the tier
+// must be positive.
+func NewCallCounterLimiter(tier Tier) (*CallCounterLimiter, error) {
+	if !(tier.MaxCounter > 0) || !(tier.DecayRate > 0) {
+		return nil, fmt.Errorf("JSON Error: Invalid tier %+v, MaxCounter and DecayRate must be positive", tier)
+	}
+	costs := make(map[string]float64, len(DefaultEndpointCosts))
+	for endpoint, cost := range DefaultEndpointCosts {
+		costs[endpoint] = cost
+	}
+	return &CallCounterLimiter{tier: tier, costs: costs, now: time.Now, sleep: sleepContext}, nil
+}
+
+// SetCost sets the increase of the counter for the calls to endpoint.
+func (l *CallCounterLimiter) SetCost(endpoint string, cost float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.costs[endpoint] = cost
+}
+
+// Cost returns the increase of the counter for the calls to endpoint,
+// 1 if not set.
+func (l *CallCounterLimiter) Cost(endpoint string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cost(endpoint)
+}
+
+func (l *CallCounterLimiter) cost(endpoint string) float64 {
+	cost, ok := l.costs[endpoint]
+	if !ok {
+		return 1
+	}
+	if cost > l.tier.MaxCounter {
+		return l.tier.MaxCounter
+	}
+	return cost
+}
+
+// decay updates the counter to now.
+func (l *CallCounterLimiter) decay(now time.Time) {
+	if !l.updated.IsZero() {
+		l.counter -= now.Sub(l.updated).Seconds() * l.tier.DecayRate
+		if l.counter < 0 {
+			l.counter = 0
+		}
+	}
+	l.updated = now
+}
+
+// Wait blocks until a call to endpoint fits in the counter and then counts it,
+// or returns the error of ctx if it is done first.
+func (l *CallCounterLimiter) Wait(ctx context.Context, endpoint string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		l.mu.Lock()
+		l.decay(l.now())
+		cost := l.cost(endpoint)
+		excess := l.counter + cost - l.tier.MaxCounter
+		if excess <= 0 {
+			l.counter += cost
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		wait := time.Duration(excess / l.tier.DecayRate * float64(time.Second))
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Exceeded fills the counter after Kraken rejected a call with
+// ErrRateLimitExceeded, so that the next calls wait for it to decay.
+// The client calls it on such errors.
+func (l *CallCounterLimiter) Exceeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decay(l.now())
+	l.counter = l.tier.MaxCounter
+}
+
+// State returns the current state of the counter.
+func (l *CallCounterLimiter) State() CallCounterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decay(l.now())
+	return CallCounterState{
+		Counter:    l.counter,
+		MaxCounter: l.tier.MaxCounter,
+		DecayRate:  l.tier.DecayRate,
+	}
+}
@@ -0,0 +1,138 @@
+package kraken
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testClock is a fake clock for the limiters, sleeping instantly.
+type testClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+// newTestLimiter returns a limiter for tier running on a testClock.
+func newTestLimiter(t *testing.T, tier Tier) (*CallCounterLimiter, *testClock) {
+	l, err := NewCallCounterLimiter(tier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{now: time.Unix(1616492376, 0)}
+	l.now, l.sleep = clock.Now, clock.Sleep
+	return l, clock
+}
+
+func Test_CallCounterLimiter_Cost(t *testing.T) {
+	l, _ := newTestLimiter(t, TierStarter)
+	testCases := []struct {
+		endpoint string
+		expected float64
+	}{
+		{endpointBalance, 1},
+		{endpointOpenOrders, 1},
+		{endpointLedgers, 2},
+		{endpointTrades, 2},
+		{endpointGetTickerInfo, 0},
+		{endpointAddOrder, 0},
+	}
+	for _, tc := range testCases {
+		if cost := l.Cost(tc.endpoint); cost != tc.expected {
+			t.Errorf("%s: cost expected %v, got %v", tc.endpoint, tc.expected, cost)
+		}
+	}
+
+	l.SetCost(endpointBalance, 3)
+	if cost := l.Cost(endpointBalance); cost != 3 {
+		t.Errorf("Cost expected 3 once set, got %v", cost)
+	}
+	if other, _ := newTestLimiter(t, TierStarter); other.Cost(endpointBalance) != 1 {
+		t.Errorf("SetCost should not change the other limiters, got %v", other.Cost(endpointBalance))
+	}
+}
+
+func Test_NewCallCounterLimiter_InvalidTier(t *testing.T) {
+	for _, tier := range []Tier{{MaxCounter: 20}, {DecayRate: 1}, {MaxCounter: 20, DecayRate: -1}} {
+		if l, err := NewCallCounterLimiter(tier); err == nil || l != nil {
+			t.Errorf("Tier %+v should be rejected", tier)
+		}
+	}
+}
+
+func Test_CallCounterLimiter_Wait(t *testing.T) {
+	l, clock := newTestLimiter(t, Tier{MaxCounter: 3, DecayRate: 50})
+	ctx := context.Background()
+
+	for _, endpoint := range []string{endpointLedgers, endpointBalance, endpointGetTickerInfo} {
+		if err := l.Wait(ctx, endpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(clock.slept) != 0 {
+		t.Errorf("Calls below the maximum should not wait, waited %v", clock.slept)
+	}
+	if state := l.State(); state.Counter != 3 || state.MaxCounter != 3 || state.DecayRate != 50 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+
+	// the counter must decay by 1 (20ms) before the next call
+	if err := l.Wait(ctx, endpointBalance); err != nil {
+		t.Fatal(err)
+	}
+	if len(clock.slept) != 1 || clock.slept[0] != 20*time.Millisecond {
+		t.Errorf("Call above the maximum should wait 20ms for the decay, waited %v", clock.slept)
+	}
+
+	clock.now = clock.now.Add(70 * time.Millisecond)
+	if state := l.State(); state.Counter != 0 {
+		t.Errorf("Counter should have decayed to 0, got %v", state.Counter)
+	}
+}
+
+func Test_CallCounterLimiter_Wait_Canceled(t *testing.T) {
+	l, err := NewCallCounterLimiter(Tier{MaxCounter: 2, DecayRate: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(context.Background(), endpointLedgers); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, endpointBalance); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if state := l.State(); state.Counter > 2 {
+		t.Errorf("A canceled call should not be counted, got %v", state.Counter)
+	}
+}
+
+func Test_Kraken_CallCounterLimiter_Exceeded(t *testing.T) {
+	k, closeServer := newPrivateTestServer(t, map[string]string{
+		"/0/private/Balance": `{"error":["EAPI:Rate limit exceeded"]}`,
+	}, nil)
+	defer closeServer()
+	l, _ := newTestLimiter(t, TierPro)
+	k.RateLimiter = l
+
+	if _, err := k.Balance(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if state := l.State(); state.Counter != state.MaxCounter {
+		t.Errorf("Counter should be filled after a rate limit error, got %v", state.Counter)
+	}
+}
@@ -0,0 +1,253 @@
+package kraken
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AssetAliases maps the common names of assets to the altnames used by Kraken.
+var AssetAliases = map[string]string{
+	"BTC":  "XBT",
+	"DOGE": "XDG",
+}
+
+// Asset is an asset known to an AssetRegistry.
+type Asset struct {
+	// ID is the canonical asset id, e.g. XXBT.
+	ID string
+	AssetInfo
+}
+
+// Pair is an asset pair known to an AssetRegistry.
+type Pair struct {
+	// ID is the canonical pair key, e.g. XXBTZEUR.
+	ID string
+	AssetPairInfo
+	// BaseAsset is the asset of the base component.
+	BaseAsset Asset
+	// QuoteAsset is the asset of the quote component.
+	QuoteAsset Asset
+}
+
+// assetIndex holds the assets and pairs of an AssetRegistry, indexed by all
+// their names.
+type assetIndex struct {
+	assets     AssetsInfoMap
+	pairs      AssetPairMap
+	assetNames map[string]string
+	pairNames  map[string]string
+	// pairAssets maps <base id>/<quote id> to the pair key.
+	pairAssets map[string]string
+}
+
+// AssetRegistry resolves the names of the assets and pairs to their canonical
+// ids, from the assets info and tradable pairs. Assets are resolved by id
+// (XXBT), altname (XBT) or alias (BTC, see AssetAliases), and pairs by key
+// (XXBTZEUR), altname (XBTEUR), wsname (XBT/EUR) or their assets names
+// (BTCEUR, BTC/EUR, BTC-EUR), regardless of case.
+// It is safe for concurrent use.
+type AssetRegistry struct {
+	k       *Kraken
+	mu      sync.RWMutex
+	index   assetIndex
+	updated time.Time
+}
+
+// NewAssetRegistry returns a registry loaded with the assets info and
+// tradable pairs given by k.
+func NewAssetRegistry(ctx context.Context, k *Kraken) (*AssetRegistry, error) {
+	r := &AssetRegistry{k: k}
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh reloads the assets info and tradable pairs. The registry is left
+// unchanged on error.
+func (r *AssetRegistry) Refresh(ctx context.Context) error {
+	assets, err := r.k.GetAssetsInfoCtx(ctx)
+	if err != nil {
+		return err
+	}
+	pairs, err := r.k.GetTradablePairsCtx(ctx)
+	if err != nil {
+		return err
+	}
+	index := newAssetIndex(*assets, *pairs)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.index = index
+	r.updated = time.Now()
+	return nil
+}
+
+// RefreshEvery refreshes the registry at every interval until ctx is done,
+// and then returns its error. The errors of the refreshes are logged and
+// the previous assets and pairs kept.
+func (r *AssetRegistry) RefreshEvery(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+				r.k.logf("kraken: asset registry refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// Updated returns the time of the last successful refresh.
+func (r *AssetRegistry) Updated() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.updated
+}
+
+// newAssetIndex indexes the given assets and pairs.
+func newAssetIndex(assets AssetsInfoMap, pairs AssetPairMap) assetIndex {
+	index := assetIndex{
+		assets:     assets,
+		pairs:      pairs,
+		assetNames: map[string]string{},
+		pairNames:  map[string]string{},
+		pairAssets: map[string]string{},
+	}
+
+	for id, info := range assets {
+		index.assetNames[normalizeName(info.Altname)] = id
+	}
+	for alias, altname := range AssetAliases {
+		if _, ok := index.assetNames[alias]; ok {
+			continue
+		}
+		if id, ok := index.assetNames[altname]; ok {
+			index.assetNames[alias] = id
+		}
+	}
+	// the ids take precedence over the other names
+	for id := range assets {
+		index.assetNames[normalizeName(id)] = id
+	}
+
+	for id, info := range pairs {
+		index.pairNames[normalizeName(info.Altname)] = id
+		if info.WSName != "" {
+			index.pairNames[normalizeName(info.WSName)] = id
+		}
+		// the dark pool pairs (.d) share the assets of the regular ones
+		if !strings.HasSuffix(id, ".d") {
+			index.pairAssets[info.Base+"/"+info.Quote] = id
+		}
+	}
+	for id := range pairs {
+		index.pairNames[normalizeName(id)] = id
+	}
+	return index
+}
+
+// normalizeName returns name in the case of the ids.
+func normalizeName(name string) string {
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// assetID returns the id of the named asset.
+func (index *assetIndex) assetID(name string) (string, bool) {
+	id, ok := index.assetNames[normalizeName(name)]
+	return id, ok
+}
+
+// pairID returns the key of the named pair.
+func (index *assetIndex) pairID(name string) (string, bool) {
+	name = normalizeName(name)
+	if id, ok := index.pairNames[name]; ok {
+		return id, true
+	}
+
+	if i := strings.IndexAny(name, "/-_"); i >= 0 {
+		return index.pairByAssets(name[:i], name[i+1:])
+	}
+	// the names of the assets are not delimited: try every split
+	for i := 1; i < len(name); i++ {
+		if id, ok := index.pairByAssets(name[:i], name[i:]); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// pairByAssets returns the key of the pair of the named base and quote assets.
+func (index *assetIndex) pairByAssets(base, quote string) (string, bool) {
+	baseID, ok := index.assetID(base)
+	if !ok {
+		return "", false
+	}
+	quoteID, ok := index.assetID(quote)
+	if !ok {
+		return "", false
+	}
+	id, ok := index.pairAssets[baseID+"/"+quoteID]
+	return id, ok
+}
+
+// asset returns the asset of the given id, with its id only if unknown.
+func (index *assetIndex) asset(id string) Asset {
+	return Asset{ID: id, AssetInfo: index.assets[id]}
+}
+
+// AssetID returns the canonical id of the named asset, e.g. XXBT for BTC.
+func (r *AssetRegistry) AssetID(name string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.index.assetID(name)
+	if !ok {
+		return "", ParseError("EQuery:Unknown asset:" + name)
+	}
+	return id, nil
+}
+
+// Asset returns the named asset.
+func (r *AssetRegistry) Asset(name string) (*Asset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.index.assetID(name)
+	if !ok {
+		return nil, ParseError("EQuery:Unknown asset:" + name)
+	}
+	asset := r.index.asset(id)
+	return &asset, nil
+}
+
+// PairID returns the canonical key of the named pair, e.g. XXBTZEUR for BTC/EUR.
+func (r *AssetRegistry) PairID(name string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.index.pairID(name)
+	if !ok {
+		return "", ParseError("EQuery:Unknown asset pair:" + name)
+	}
+	return id, nil
+}
+
+// Pair returns the named pair, with its base and quote assets.
+func (r *AssetRegistry) Pair(name string) (*Pair, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.index.pairID(name)
+	if !ok {
+		return nil, ParseError("EQuery:Unknown asset pair:" + name)
+	}
+	info := r.index.pairs[id]
+	return &Pair{
+		ID:            id,
+		AssetPairInfo: info,
+		BaseAsset:     r.index.asset(info.Base),
+		QuoteAsset:    r.index.asset(info.Quote),
+	}, nil
+}
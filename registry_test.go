@@ -0,0 +1,154 @@
+package kraken
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_AssetRegistry(t *testing.T) {
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
+
+	r, err := NewAssetRegistry(context.Background(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assets := []struct {
+		name     string
+		expected string
+	}{
+		{"XXBT", "XXBT"},
+		{"XBT", "XXBT"},
+		{"BTC", "XXBT"},
+		{"btc", "XXBT"},
+		{"ZEUR", "ZEUR"},
+		{"EUR", "ZEUR"},
+		{" eth ", "XETH"},
+	}
+	for _, tc := range assets {
+		if id, err := r.AssetID(tc.name); err != nil || id != tc.expected {
+			t.Errorf("Asset %q expected: %s, got: %s %v", tc.name, tc.expected, id, err)
+		}
+	}
+
+	pairs := []struct {
+		name     string
+		expected string
+	}{
+		{"XXBTZEUR", "XXBTZEUR"},
+		{"XBTEUR", "XXBTZEUR"},
+		{"XBT/EUR", "XXBTZEUR"},
+		{"BTC/EUR", "XXBTZEUR"},
+		{"btc-usd", "XXBTZUSD"},
+		{"BTCEUR", "XXBTZEUR"},
+		{"ETHXBT", "XETHXXBT"},
+		{"ETH/BTC", "XETHXXBT"},
+		{"XETCZEUR", "XETCZEUR"},
+	}
+	for _, tc := range pairs {
+		if id, err := r.PairID(tc.name); err != nil || id != tc.expected {
+			t.Errorf("Pair %q expected: %s, got: %s %v", tc.name, tc.expected, id, err)
+		}
+	}
+
+	pair, err := r.Pair("BTC/EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.ID != "XXBTZEUR" || pair.Altname != "XBTEUR" || pair.WSName != "XBT/EUR" || pair.PairDecimals != 1 {
+		t.Errorf("Unexpected pair: %+v", pair)
+	}
+	if pair.BaseAsset.ID != "XXBT" || pair.BaseAsset.Altname != "XBT" ||
+		pair.QuoteAsset.ID != "ZEUR" || pair.QuoteAsset.Decimals != 4 {
+		t.Errorf("Unexpected base and quote: %+v %+v", pair.BaseAsset, pair.QuoteAsset)
+	}
+
+	asset, err := r.Asset("BTC")
+	if err != nil || asset.ID != "XXBT" || asset.DisplayDecimals != 5 {
+		t.Errorf("Unexpected asset: %+v %v", asset, err)
+	}
+
+	if _, err := r.AssetID("DOGE"); !errors.Is(err, ErrUnknownAsset) {
+		t.Errorf("Expected ErrUnknownAsset, got: %v", err)
+	}
+	for _, name := range []string{"XBTJPY", "BTC/JPY", "EURXBT", ""} {
+		if _, err := r.PairID(name); !errors.Is(err, ErrUnknownAssetPair) {
+			t.Errorf("%q: expected ErrUnknownAssetPair, got: %v", name, err)
+		}
+	}
+}
+
+func Test_AssetRegistry_Refresh(t *testing.T) {
+	routes := map[string]string{
+		"/0/public/Assets":     publicRoutes["/0/public/Assets"],
+		"/0/public/AssetPairs": `{"error":[],"result":{}}`,
+	}
+	k, closeServer := newTestServer(routes)
+	defer closeServer()
+
+	r, err := NewAssetRegistry(context.Background(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.PairID("XBTEUR"); err == nil {
+		t.Error("The pair should not be known before the refresh")
+	}
+	loaded := r.Updated()
+
+	setTestRoute(routes, "/0/public/AssetPairs", publicRoutes["/0/public/AssetPairs"])
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.PairID("XBTEUR"); err != nil {
+		t.Errorf("The pair should be known after the refresh, got: %v", err)
+	}
+	if !r.Updated().After(loaded) {
+		t.Error("Updated should give the last refresh")
+	}
+
+	// a failed refresh keeps the registry
+	setTestRoute(routes, "/0/public/Assets", `{"error":["EService:Unavailable"]}`)
+	refreshed := r.Updated()
+	if err := r.Refresh(context.Background()); !errors.Is(err, ErrServiceUnavailable) {
+		t.Errorf("Expected ErrServiceUnavailable, got: %v", err)
+	}
+	if _, err := r.PairID("XBTEUR"); err != nil {
+		t.Errorf("The pair should be kept after a failed refresh, got: %v", err)
+	}
+	if !r.Updated().Equal(refreshed) {
+		t.Error("A failed refresh should not change Updated")
+	}
+}
+
+func Test_AssetRegistry_RefreshEvery(t *testing.T) {
+	k, closeServer := newTestServer(publicRoutes)
+	defer closeServer()
+
+	r, err := NewAssetRegistry(context.Background(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := r.Updated()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.RefreshEvery(ctx, time.Millisecond)
+	}()
+
+	// wait for a refresh, however long the ticks take
+	deadline := time.Now().Add(5 * time.Second)
+	for !r.Updated().After(loaded) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if !r.Updated().After(loaded) {
+		t.Error("RefreshEvery should refresh the registry")
+	}
+}
@@ -0,0 +1,123 @@
+package kraken
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+/* Defaults of the ExponentialBackoff policy. */
+const (
+	DefaultRetryInitialInterval = 500 * time.Millisecond
+	DefaultRetryMaxInterval     = 30 * time.Second
+	DefaultRetryMultiplier      = 2
+	DefaultRetryJitter          = 0.5
+	DefaultRetryMaxElapsedTime  = 2 * time.Minute
+)
+
+// ExponentialBackoff is a RetryPolicy retrying the temporary failures
+// (see IsTemporary) after exponentially growing, randomized delays.
+// A longer delay asked by the Retry-After header of an HTTPError is honoured.
+// Its zero fields take the DefaultRetry* values, except Jitter and MaxAttempts.
+type ExponentialBackoff struct {
+	// InitialInterval is the delay after the first failed attempt.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay (before jitter), a negative one meaning no cap.
+	MaxInterval time.Duration
+	// Multiplier of the delay after every failed attempt.
+	Multiplier float64
+	// Jitter is the randomization factor, between 0 and 1: the delay d is
+	// picked in [d*(1-Jitter), d*(1+Jitter)].
+	Jitter float64
+	// MaxElapsedTime after which the call is given up, a negative one
+	// meaning no limit.
+	MaxElapsedTime time.Duration
+	// MaxAttempts after which the call is given up, 0 meaning no limit.
+	MaxAttempts int
+}
+
+// NewExponentialBackoff returns an ExponentialBackoff with the default settings.
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval: DefaultRetryInitialInterval,
+		MaxInterval:     DefaultRetryMaxInterval,
+		Multiplier:      DefaultRetryMultiplier,
+		Jitter:          DefaultRetryJitter,
+		MaxElapsedTime:  DefaultRetryMaxElapsedTime,
+	}
+}
+
+// withDefaults returns b with its zero fields set to the default values.
+func (b ExponentialBackoff) withDefaults() ExponentialBackoff {
+	if b.InitialInterval == 0 {
+		b.InitialInterval = DefaultRetryInitialInterval
+	}
+	if b.MaxInterval == 0 {
+		b.MaxInterval = DefaultRetryMaxInterval
+	}
+	if b.Multiplier == 0 {
+		b.Multiplier = DefaultRetryMultiplier
+	}
+	if b.MaxElapsedTime == 0 {
+		b.MaxElapsedTime = DefaultRetryMaxElapsedTime
+	}
+	return b
+}
+
+// Backoff implements RetryPolicy.
+func (b *ExponentialBackoff) Backoff(attempt int, elapsed time.Duration, err error) (time.Duration, bool) {
+	if !IsTemporary(err) || (b.MaxAttempts > 0 && attempt >= b.MaxAttempts) {
+		return 0, false
+	}
+	p := b.withDefaults()
+
+	d := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && d > float64(p.MaxInterval) {
+		d = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	delay := time.Duration(d)
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if retryAfter := httpErr.RetryAfter(); retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	if p.MaxElapsedTime > 0 && elapsed+delay > p.MaxElapsedTime {
+		return 0, false
+	}
+	return delay, true
+}
+
+// IsTemporary reports whether err is a transient failure, after which a call
+// may be attempted again: a network timeout, a refused or reset connection,
+// a truncated response, a 5xx response, or an EService:Unavailable or
+// EService:Busy message. Other network errors, e.g. of TLS certificates or
+// malformed URLs, are not.
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrServiceBusy) {
+		return true
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
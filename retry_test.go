@@ -0,0 +1,217 @@
+package kraken
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_IsTemporary(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{APIError{"EService:Unavailable"}, true},
+		{APIError{"EService:Busy"}, true},
+		{APIError{"EOrder:Insufficient funds"}, false},
+		{&HTTPError{StatusCode: http.StatusBadGateway}, true},
+		{&HTTPError{StatusCode: http.StatusOK, Err: errors.New("invalid character '<'")}, false},
+		{&HTTPError{StatusCode: http.StatusNotFound}, false},
+		{testURLError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{testURLError(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{testURLError(testTimeoutError{}), true},
+		{testURLError(io.ErrUnexpectedEOF), true},
+		{io.ErrUnexpectedEOF, true},
+		{testURLError(x509.UnknownAuthorityError{}), false},
+		{testURLError(errors.New("unsupported protocol scheme \"ftp\"")), false},
+		{testURLError(&net.DNSError{Err: "no such host", Name: "api.kraken.invalid"}), false},
+		{testURLError(errors.New("connection refused")), false},
+		{errors.New("JSON Error: Parameter order cannot be empty"), false},
+	}
+	for _, tc := range testCases {
+		if IsTemporary(tc.err) != tc.expected {
+			t.Errorf("IsTemporary(%v) expected %v", tc.err, tc.expected)
+		}
+	}
+}
+
+// testURLError returns err as returned by the http.Client.
+func testURLError(err error) error {
+	return &url.Error{Op: "Post", URL: "https://api.kraken.com/0/private/Balance", Err: err}
+}
+
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+func Test_ExponentialBackoff(t *testing.T) {
+	b := &ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+		MaxElapsedTime:  5 * time.Second,
+	}
+	busy := APIError{"EService:Busy"}
+	testCases := []struct {
+		attempt  int
+		elapsed  time.Duration
+		err      error
+		expected time.Duration
+		retry    bool
+	}{
+		{1, 0, busy, 100 * time.Millisecond, true},
+		{2, 0, busy, 200 * time.Millisecond, true},
+		{4, 0, busy, 800 * time.Millisecond, true},
+		{6, 0, busy, time.Second, true},
+		{6, 4500 * time.Millisecond, busy, 0, false},
+		{1, 0, APIError{"EGeneral:Invalid arguments"}, 0, false},
+		{1, 0, &HTTPError{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"3"}}}, 3 * time.Second, true},
+	}
+	for _, tc := range testCases {
+		delay, retry := b.Backoff(tc.attempt, tc.elapsed, tc.err)
+		if delay != tc.expected || retry != tc.retry {
+			t.Errorf("Attempt %d after %v (%v): expected %v %v, got %v %v",
+				tc.attempt, tc.elapsed, tc.err, tc.expected, tc.retry, delay, retry)
+		}
+	}
+
+	b.MaxAttempts = 3
+	if _, retry := b.Backoff(3, 0, busy); retry {
+		t.Error("Should not retry after MaxAttempts")
+	}
+
+	// the zero value takes the defaults, without jitter
+	b = &ExponentialBackoff{}
+	if delay, retry := b.Backoff(1, 0, busy); delay != DefaultRetryInitialInterval || !retry {
+		t.Errorf("Zero value: expected %v true, got %v %v", DefaultRetryInitialInterval, delay, retry)
+	}
+	if delay, _ := b.Backoff(20, 0, busy); delay != DefaultRetryMaxInterval {
+		t.Errorf("Zero value: delay expected capped at %v, got %v", DefaultRetryMaxInterval, delay)
+	}
+	if _, retry := b.Backoff(2, DefaultRetryMaxElapsedTime, busy); retry {
+		t.Error("Zero value: should not retry after DefaultRetryMaxElapsedTime")
+	}
+	b.MaxInterval, b.MaxElapsedTime = -1, -1
+	if delay, retry := b.Backoff(20, 24*time.Hour, busy); delay <= DefaultRetryMaxInterval || !retry {
+		t.Errorf("Negative limits should not cap the delay, got %v %v", delay, retry)
+	}
+
+	b = NewExponentialBackoff()
+	for i := 0; i < 100; i++ {
+		delay, retry := b.Backoff(2, 0, busy)
+		if !retry || delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Fatalf("Jittered delay expected in [500ms, 1.5s], got %v %v", delay, retry)
+		}
+	}
+}
+
+func Test_Kraken_ExponentialBackoff(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, publicRoutes["/0/public/Time"])
+	}))
+	defer srv.Close()
+
+	k := NewClient(WithBaseURL(srv.URL),
+		WithRetryPolicy(&ExponentialBackoff{InitialInterval: time.Millisecond, Multiplier: 2}))
+	if _, err := k.GetServerTime(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func testOpenOrder(userRef int32, openTime time.Time) string {
+	return fmt.Sprintf(`{"error":[],"result":{"open":{"OQCLML-BW3P3-BUCMWZ":{"refid":null,"userref":%d,
+	"status":"open","opentm":%d.25,"starttm":0,"expiretm":0,"descr":{"pair":"XBTEUR","type":"buy",
+	"ordertype":"limit","price":"37500.0","price2":"0","leverage":"none","order":"buy 1.25000000 XBTEUR @ limit 37500.0",
+	"close":""},"vol":"1.25000000","vol_exec":"0.00000000","cost":"0.0","fee":"0.00000","price":"0.0",
+	"stopprice":"0.00000","limitprice":"0.00000","misc":"","oflags":"fciq"}}}}`, userRef, openTime.Unix())
+}
+
+func Test_Kraken_AddOrder_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		userRef  int32
+		open     string
+		txid     string
+		addCalls int
+	}{
+		{"placed", 42, testOpenOrder(42, time.Now()), "OQCLML-BW3P3-BUCMWZ", 1},
+		{"older order", 42, testOpenOrder(42, time.Now().Add(-time.Hour)), "OUF4EM-FRGI2-MQMWZD", 2},
+		{"not placed", 42, `{"error":[],"result":{"open":{}}}`, "OUF4EM-FRGI2-MQMWZD", 2},
+		{"no userref", 0, `{"error":[],"result":{"open":{}}}`, "", 1},
+	}
+	for _, tc := range testCases {
+		routes := map[string]string{
+			"/0/public/AssetPairs":    publicRoutes["/0/public/AssetPairs"],
+			"/0/private/AddOrder":     "",
+			"/0/private/OpenOrders":   tc.open,
+			"/0/private/ClosedOrders": `{"error":[],"result":{"closed":{},"count":0}}`,
+		}
+		var addCalls int
+		k, closeServer := newPrivateTestServer(t, routes, func(path string, form url.Values) {
+			switch path {
+			case "/0/private/AddOrder":
+				addCalls++
+				if addCalls == 1 {
+					routes[path] = `{"error":["EService:Unavailable"]}`
+				} else {
+					routes[path] = `{"error":[],"result":{"descr":{"order":"buy 1.25000000 XBTEUR @ limit 37500.0"},"txid":["OUF4EM-FRGI2-MQMWZD"]}}`
+				}
+			case "/0/private/OpenOrders", "/0/private/ClosedOrders":
+				if form.Get("userref") != "42" {
+					t.Errorf("%s: orders should be looked up by userref, got: %v", tc.name, form)
+				}
+			}
+		})
+		k.RetryPolicy = &ExponentialBackoff{InitialInterval: time.Millisecond, Multiplier: 2, MaxAttempts: 3}
+
+		r, err := k.AddOrder(context.Background(), &AddOrderRequest{
+			Pair:      "XBTEUR",
+			Type:      OrderBuy,
+			OrderType: OrderTypeLimit,
+			Price:     "37500.0",
+			Volume:    "1.25",
+			UserRef:   tc.userRef,
+		})
+		closeServer()
+
+		if addCalls != tc.addCalls {
+			t.Errorf("%s: expected %d AddOrder calls, got %d", tc.name, tc.addCalls, addCalls)
+		}
+		if tc.txid == "" {
+			if !errors.Is(err, ErrServiceUnavailable) {
+				t.Errorf("%s: expected ErrServiceUnavailable, got: %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(r.TxID) != 1 || r.TxID[0] != tc.txid {
+			t.Errorf("%s: expected txid %s, got: %v", tc.name, tc.txid, r.TxID)
+		}
+	}
+}
@@ -2,19 +2,177 @@ package kraken
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	urlBaseURL          string = "https://api.kraken.com/0/public/"
-	urlGetServerTime    string = urlBaseURL + "Time"
-	urlGetAssetsInfo    string = urlBaseURL + "Assets"
-	urlGetTradablePairs string = urlBaseURL + "AssetPairs"
-	urlGetTickerInfo    string = urlBaseURL + "Ticker"
-	urlGetOHLCData      string = urlBaseURL + "OHLC"
-	urlGetOrderBook     string = urlBaseURL + "Depth"
-	urlGetTrades        string = urlBaseURL + "Trades"
+	// DefaultBaseURL is the scheme and host of the Kraken REST API.
+	DefaultBaseURL string = "https://api.kraken.com"
+	// DefaultAPIVersion is the API version path segment.
+	DefaultAPIVersion string = "0"
+)
+
+/* Endpoint names, relative to <base url>/<api version>/public/. */
+const (
+	endpointGetServerTime    string = "Time"
+	endpointGetAssetsInfo    string = "Assets"
+	endpointGetTradablePairs string = "AssetPairs"
+	endpointGetTickerInfo    string = "Ticker"
+	endpointGetOHLCData      string = "OHLC"
+	endpointGetOrderBook     string = "Depth"
+	endpointGetTrades        string = "Trades"
+)
+
+/* Endpoint names, relative to <base url>/<api version>/private/. */
+const (
+	endpointBalance      string = "Balance"
+	endpointTradeBalance string = "TradeBalance"
+	endpointAddOrder     string = "AddOrder"
+	endpointCancelOrder  string = "CancelOrder"
+	endpointCancelAll    string = "CancelAll"
+	endpointCancelAfter  string = "CancelAllOrdersAfter"
+	endpointEditOrder    string = "EditOrder"
+	endpointAddBatch     string = "AddOrderBatch"
+	endpointCancelBatch  string = "CancelOrderBatch"
+	endpointOpenOrders   string = "OpenOrders"
+	endpointClosedOrders string = "ClosedOrders"
+	endpointQueryOrders  string = "QueryOrders"
+	endpointTrades       string = "TradesHistory"
+	endpointQueryTrades  string = "QueryTrades"
+	endpointPositions    string = "OpenPositions"
+	endpointLedgers      string = "Ledgers"
+	endpointQueryLedgers string = "QueryLedgers"
+	endpointTradeVolume  string = "TradeVolume"
+	endpointWSToken      string = "GetWebSocketsToken"
+
+	endpointDepositMethods   string = "DepositMethods"
+	endpointDepositAddresses string = "DepositAddresses"
+	endpointDepositStatus    string = "DepositStatus"
+	endpointWithdrawInfo     string = "WithdrawInfo"
+	endpointWithdraw         string = "Withdraw"
+	endpointWithdrawStatus   string = "WithdrawStatus"
+	endpointWithdrawCancel   string = "WithdrawCancel"
+
+	endpointStakingAssets       string = "Staking/Assets"
+	endpointStake               string = "Stake"
+	endpointUnstake             string = "Unstake"
+	endpointStakingPending      string = "Staking/Pending"
+	endpointStakingTransactions string = "Staking/Transactions"
+
+	endpointAddExport      string = "AddExport"
+	endpointExportStatus   string = "ExportStatus"
+	endpointRetrieveExport string = "RetrieveExport"
+	endpointRemoveExport   string = "RemoveExport"
+)
+
+// AddOrderBatchLimit is the maximum number of orders per AddOrderBatch call.
+const AddOrderBatchLimit = 15
+
+// CancelOrderBatchLimit is the maximum number of orders per CancelOrderBatch call.
+const CancelOrderBatchLimit = 50
+
+// QueryOrdersLimit is the maximum number of transaction ids per QueryOrders call.
+const QueryOrdersLimit = 50
+
+// QueryTradesLimit is the maximum number of transaction ids per QueryTrades call.
+const QueryTradesLimit = 20
+
+// QueryLedgersLimit is the maximum number of ledger ids per QueryLedgers call.
+const QueryLedgersLimit = 20
+
+/* Ledger types for the Ledgers filter. */
+const (
+	LedgerTypeAll        = "all"
+	LedgerTypeDeposit    = "deposit"
+	LedgerTypeWithdrawal = "withdrawal"
+	LedgerTypeTrade      = "trade"
+	LedgerTypeMargin     = "margin"
+	LedgerTypeRollover   = "rollover"
+	LedgerTypeCredit     = "credit"
+	LedgerTypeTransfer   = "transfer"
+	LedgerTypeSettled    = "settled"
+	LedgerTypeStaking    = "staking"
+	LedgerTypeSale       = "sale"
+)
+
+/* Trade types for the TradesHistory filter. */
+const (
+	TradeTypeAll             = "all"
+	TradeTypeAnyPosition     = "any position"
+	TradeTypeClosedPosition  = "closed position"
+	TradeTypeClosingPosition = "closing position"
+	TradeTypeNoPosition      = "no position"
+)
+
+/* Report types of the data exports. */
+const (
+	ExportTrades  = "trades"
+	ExportLedgers = "ledgers"
+)
+
+/* Statuses of the data exports. */
+const (
+	ExportStatusQueued     = "Queued"
+	ExportStatusProcessing = "Processing"
+	ExportStatusProcessed  = "Processed"
+)
+
+/* Removal types of the data exports. */
+const (
+	ExportRemoveCancel = "cancel"
+	ExportRemoveDelete = "delete"
+)
+
+// DefaultExportPollInterval is the interval between the status checks of
+// a report being prepared, when none is given.
+const DefaultExportPollInterval = 5 * time.Second
+
+// exportRemoveTimeout bounds the removal of a report after its export.
+const exportRemoveTimeout = 30 * time.Second
+
+/* Order directions. */
+const (
+	OrderBuy  = "buy"
+	OrderSell = "sell"
+)
+
+/* Order types. */
+const (
+	OrderTypeMarket          = "market"
+	OrderTypeLimit           = "limit"
+	OrderTypeStopLoss        = "stop-loss"
+	OrderTypeTakeProfit      = "take-profit"
+	OrderTypeStopLossLimit   = "stop-loss-limit"
+	OrderTypeTakeProfitLimit = "take-profit-limit"
+	OrderTypeSettlePosition  = "settle-position"
+)
+
+/* Order statuses. */
+const (
+	OrderStatusPending  = "pending"
+	OrderStatusOpen     = "open"
+	OrderStatusClosed   = "closed"
+	OrderStatusCanceled = "canceled"
+	OrderStatusExpired  = "expired"
+)
+
+/* Order flags. */
+const (
+	// OrderFlagPost makes a post-only limit order.
+	OrderFlagPost = "post"
+	// OrderFlagFeeInBase prefers the fee in base currency.
+	OrderFlagFeeInBase = "fcib"
+	// OrderFlagFeeInQuote prefers the fee in quote currency.
+	OrderFlagFeeInQuote = "fciq"
+	// OrderFlagNoMarketPriceProtection disables the market price protection.
+	OrderFlagNoMarketPriceProtection = "nompp"
+	// OrderFlagVolumeInQuote gives the volume in quote currency (market buy orders only).
+	OrderFlagVolumeInQuote = "viqc"
 )
 
 /* Some of the common pairs for convenience. */
@@ -41,9 +199,6 @@ const (
 	XXBTZUSD = "XXBTZUSD"
 )
 
-// APIError represents JSON error type.
-type APIError []string
-
 // ServerTime contains server unix timestamp and string date.
 type ServerTime struct {
 	Unixtime int64  `json:"unixtime"`
@@ -92,6 +247,8 @@ type FeeInfo []float32
 type AssetPairInfo struct {
 	// Alternate pair name.
 	Altname string `json:"altname"`
+	// WebSocket pair name, e.g. XBT/EUR (if available).
+	WSName string `json:"wsname"`
 	// Asset class of base component.
 	AclassBase string `json:"aclass_base"`
 	// Asset id of base component.
@@ -124,6 +281,25 @@ type AssetPairInfo struct {
 	MarginStop byte `json:"margin_stop"`
 }
 
+// FeeForVolume returns the percent fee of the tier the 30 days volume falls in
+// (in FeeVolumeCurrency), from the maker schedule if maker is set and the pair
+// is on maker/taker fees, from the taker schedule otherwise.
+func (t AssetPairInfo) FeeForVolume(volume float64, maker bool) float32 {
+	schedule := t.Fees
+	if maker && len(t.FeesMaker) > 0 {
+		schedule = t.FeesMaker
+	}
+
+	var fee float32
+	for _, tier := range schedule {
+		if len(tier) < 2 || float64(tier[0]) > volume {
+			break
+		}
+		fee = tier[1]
+	}
+	return fee
+}
+
 // AssetPairMap maps AssetsPair data to currency pair.
 type AssetPairMap map[string]AssetPairInfo
 
@@ -133,26 +309,112 @@ type AssetPairResult struct {
 	Error  APIError     `json:"error"`
 }
 
-// TickerInfo contains the current ticker info.
+// TickerQuote is the best ask or bid of a ticker.
+type TickerQuote struct {
+	Price          Decimal
+	WholeLotVolume Decimal
+	LotVolume      Decimal
+}
+
+// TickerTrade is the last trade closed of a ticker.
+type TickerTrade struct {
+	Price     Decimal
+	LotVolume Decimal
+}
+
+// TickerValues holds a ticker value for today and the last 24 hours.
+type TickerValues struct {
+	Today   Decimal
+	Last24h Decimal
+}
+
+// TickerCounts holds a ticker count for today and the last 24 hours.
+type TickerCounts struct {
+	Today   int
+	Last24h int
+}
+
+// TickerInfo contains the current ticker info, parsed from the arrays given
+// by Kraken, which are kept in Raw.
 type TickerInfo struct {
+	Ask       TickerQuote
+	Bid       TickerQuote
+	LastTrade TickerTrade
+	Volume    TickerValues
+	// Volume weighted average price.
+	VWAP       TickerValues
+	TradeCount TickerCounts
+	Low        TickerValues
+	High       TickerValues
+	// Today's opening price.
+	Open Decimal
+	// Raw ticker info.
+	Raw RawTickerInfo
+}
+
+// RawTickerInfo contains the ticker info as given by Kraken.
+type RawTickerInfo struct {
 	// Ask array(<price>, <whole lot volume>, <lot volume>).
-	A []string
+	A []Decimal `json:"a"`
 	// Bid array(<price>, <whole lot volume>, <lot volume>).
-	B []string
+	B []Decimal `json:"b"`
 	// Last trade closed array(<price>, <lot volume>).
-	C []string
+	C []Decimal `json:"c"`
 	// Volume array(<today>, <last 24 hours>).
-	V []string
+	V []Decimal `json:"v"`
 	// Volume weighted average price array(<today>, <last 24 hours>).
-	P []string
+	P []Decimal `json:"p"`
 	// Number of trades array(<today>, <last 24 hours>).
-	T []int
+	T []int `json:"t"`
 	// Low array(<today>, <last 24 hours>).
-	L []string
+	L []Decimal `json:"l"`
 	// High array(<today>, <last 24 hours>).
-	H []string
+	H []Decimal `json:"h"`
 	// Today's opening price.
-	O string
+	O Decimal `json:"o"`
+}
+
+// UnmarshalJSON of the TickerInfo, parsing its arrays into the named fields.
+// Missing values are left zero.
+func (t *TickerInfo) UnmarshalJSON(b []byte) error {
+	var raw RawTickerInfo
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	at := func(values []Decimal, i int) Decimal {
+		if i < len(values) {
+			return values[i]
+		}
+		return Decimal{}
+	}
+	values := func(v []Decimal) TickerValues {
+		return TickerValues{Today: at(v, 0), Last24h: at(v, 1)}
+	}
+
+	*t = TickerInfo{
+		Ask:       TickerQuote{Price: at(raw.A, 0), WholeLotVolume: at(raw.A, 1), LotVolume: at(raw.A, 2)},
+		Bid:       TickerQuote{Price: at(raw.B, 0), WholeLotVolume: at(raw.B, 1), LotVolume: at(raw.B, 2)},
+		LastTrade: TickerTrade{Price: at(raw.C, 0), LotVolume: at(raw.C, 1)},
+		Volume:    values(raw.V),
+		VWAP:      values(raw.P),
+		Low:       values(raw.L),
+		High:      values(raw.H),
+		Open:      raw.O,
+		Raw:       raw,
+	}
+	if len(raw.T) > 0 {
+		t.TradeCount.Today = raw.T[0]
+	}
+	if len(raw.T) > 1 {
+		t.TradeCount.Last24h = raw.T[1]
+	}
+	return nil
+}
+
+// MarshalJSON writes the raw ticker info, in the format of Kraken.
+func (t TickerInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Raw)
 }
 
 // TickerInfoMap maps currency to TickerInfo
@@ -164,40 +426,57 @@ type TickerInfoResult struct {
 	Error  APIError      `json:"error"`
 }
 
-// OHLCEntry has a single OHLC entry.
+// OHLCEntry has a single OHLC entry, given by Kraken as the array
+// [<time>, <open>, <high>, <low>, <close>, <vwap>, <volume>, <count>].
 type OHLCEntry struct {
 	Timestamp time.Time
-	Data      [6]string
-	Count     int64
+	Open      Decimal
+	High      Decimal
+	Low       Decimal
+	Close     Decimal
+	// Volume weighted average price.
+	VWAP   Decimal
+	Volume Decimal
+	// Number of trades.
+	Count int64
 }
 
 // UnmarshalJSON for custom marchaling of OHLCEntry
 func (c *OHLCEntry) UnmarshalJSON(b []byte) error {
-	tmp := [8]json.Number{}
-	if err := json.Unmarshal(b, &tmp); err != nil {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
 		return err
 	}
-
-	tmpTimeSec, err := tmp[0].Int64()
-	if err != nil {
-		return err
+	if len(raw) != 8 {
+		return fmt.Errorf("JSON Error: OHLC entry has %d values, expected 8", len(raw))
 	}
-	c.Timestamp = time.Unix(tmpTimeSec, 0)
 
-	var data [6]string
-	for i := 1; i < 7; i++ {
-		data[0] = tmp[i].String()
+	var entry OHLCEntry
+	var timestamp json.Number
+	fields := [8]interface{}{&timestamp, &entry.Open, &entry.High, &entry.Low,
+		&entry.Close, &entry.VWAP, &entry.Volume, &entry.Count}
+	for i, field := range fields {
+		if err := json.Unmarshal(raw[i], field); err != nil {
+			return err
+		}
 	}
 
-	c.Data = data
-	c.Count, err = tmp[7].Int64()
+	tmpTimeSec, err := timestamp.Int64()
 	if err != nil {
 		return err
 	}
+	entry.Timestamp = time.Unix(tmpTimeSec, 0)
 
+	*c = entry
 	return nil
 }
 
+// MarshalJSON writes the OHLCEntry in the array format of Kraken.
+func (c OHLCEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal([8]interface{}{c.Timestamp.Unix(), c.Open, c.High, c.Low,
+		c.Close, c.VWAP, c.Volume, c.Count})
+}
+
 // OHLCEntryData contains the result from the JSON API call.
 type OHLCEntryData struct {
 	Data []OHLCEntry
@@ -229,8 +508,8 @@ func NewOHLCQueryOptions() *OHLCQueryOptions {
 // OrderBookEntry represents a single entry: price, volume, timestamp
 type OrderBookEntry struct {
 	Timestamp time.Time
-	Price     string
-	Volume    string
+	Price     Decimal
+	Volume    Decimal
 }
 
 // UnmarshalJSON of the OrderBookEntry
@@ -244,8 +523,13 @@ func (o *OrderBookEntry) UnmarshalJSON(b []byte) error {
 		return err1
 	}
 	o.Timestamp = time.Unix(tmpTime, 0)
-	o.Price = tmp[0].String()
-	o.Volume = tmp[1].String()
+	var err error
+	if o.Price, err = ParseDecimal(tmp[0].String()); err != nil {
+		return err
+	}
+	if o.Volume, err = ParseDecimal(tmp[1].String()); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -288,11 +572,25 @@ type OrderBookResult struct {
 	Error  APIError     `json:"error"`
 }
 
+// parseTimestamp converts Kraken's unix time in seconds, with up to four
+// decimals, into a time. Zero or empty means no time.
+func parseTimestamp(n json.Number) (time.Time, error) {
+	if n == "" {
+		return time.Time{}, nil
+	}
+	tmpTimeFloat, err := n.Float64()
+	if err != nil || tmpTimeFloat == 0 {
+		return time.Time{}, err
+	}
+	var tmpTimeMilliseconds = int64(tmpTimeFloat * 10000)
+	return time.Unix(0, tmpTimeMilliseconds*int64(100000)), nil
+}
+
 // Trade represents single trade
 type Trade struct {
 	Timestamp time.Time
-	Price     string
-	Volume    string
+	Price     Decimal
+	Volume    Decimal
 	BS        string
 	ML        string
 	MISC      string
@@ -314,12 +612,10 @@ func (t *Trade) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(tmp[2], &tmpTimeJSON); err != nil {
 		return err
 	}
-	tmpTimeFloat, err := tmpTimeJSON.Float64()
-	if err != nil {
+	var err error
+	if t.Timestamp, err = parseTimestamp(tmpTimeJSON); err != nil {
 		return err
 	}
-	var tmpTimeMilliseconds = int64(tmpTimeFloat * 10000)
-	t.Timestamp = time.Unix(0, tmpTimeMilliseconds*int64(100000))
 	if err := json.Unmarshal(tmp[3], &t.BS); err != nil {
 		return err
 	}
@@ -368,3 +664,1236 @@ type TradesResult struct {
 	Result TradeBook `json:"result"`
 	Error  APIError  `json:"error"`
 }
+
+// BalanceMap maps asset id (as in AssetsInfoMap) to the account balance.
+type BalanceMap map[string]string
+
+// TradeBalanceOptions contains the query parameters for the trade balance request.
+type TradeBalanceOptions struct {
+	// Asset class (optional): currency (default).
+	AssetClass string
+	// Base asset used to determine balance (optional): ZUSD (default).
+	Asset string
+}
+
+// TradeBalanceInfo contains the trade balance of the account,
+// expressed in the base asset.
+type TradeBalanceInfo struct {
+	// Equivalent balance (combined balance of all currencies).
+	EquivalentBalance string `json:"eb"`
+	// Trade balance (combined balance of all equity currencies).
+	TradeBalance string `json:"tb"`
+	// Margin amount of open positions.
+	Margin string `json:"m"`
+	// Unrealized net profit/loss of open positions.
+	UnrealizedNetPL string `json:"n"`
+	// Cost basis of open positions.
+	CostBasis string `json:"c"`
+	// Current floating valuation of open positions.
+	Valuation string `json:"v"`
+	// Equity = trade balance + unrealized net profit/loss.
+	Equity string `json:"e"`
+	// Free margin = equity - initial margin (maximum margin available to open new positions).
+	FreeMargin string `json:"mf"`
+	// Margin level = (equity / initial margin) * 100.
+	MarginLevel string `json:"ml"`
+}
+
+// CloseOrder is the conditional close order attached to an order,
+// placed once the order is filled.
+type CloseOrder struct {
+	// Order type: limit, stop-loss, take-profit, stop-loss-limit, take-profit-limit.
+	OrderType string
+	// Price (optional, depending on the order type).
+	Price string
+	// Secondary price (optional, depending on the order type).
+	Price2 string
+}
+
+// AddOrderRequest contains the parameters of a new order.
+type AddOrderRequest struct {
+	// Asset pair, either its key or its altname.
+	Pair string
+	// Type of order: buy or sell.
+	Type string
+	// Order type: market, limit, stop-loss, take-profit, stop-loss-limit,
+	// take-profit-limit, settle-position.
+	OrderType string
+	// Price (optional, depending on the order type). Can be preceded by
+	// +, - or # for a relative price, and followed by % for a percentage.
+	Price string
+	// Secondary price (optional, depending on the order type).
+	Price2 string
+	// Order volume in lots. With a leverage, 0 closes the whole margin
+	// position.
+	Volume string
+	// Amount of leverage desired (optional), e.g. 2 or 2:1. Default none.
+	Leverage string
+	// Order flags (optional): post, fcib, fciq, nompp, viqc.
+	OFlags []string
+	// Scheduled start time (optional): 0 now (default), +<n> seconds from now,
+	// <n> unix timestamp.
+	StartTime string
+	// Expiration time (optional): 0 no expiration (default), +<n> seconds
+	// from now, <n> unix timestamp.
+	ExpireTime string
+	// User reference id (optional), zero meaning none.
+	UserRef int32
+	// Validate inputs only, do not submit the order.
+	ValidateOnly bool
+	// Conditional close order (optional).
+	Close *CloseOrder
+}
+
+// OrderDescription is the human readable description of an order.
+// AddOrder only fills Order and Close.
+type OrderDescription struct {
+	// Asset pair.
+	Pair string `json:"pair"`
+	// Type of order (buy/sell).
+	Type string `json:"type"`
+	// Order type.
+	OrderType string `json:"ordertype"`
+	// Primary price.
+	Price string `json:"price"`
+	// Secondary price.
+	Price2 string `json:"price2"`
+	// Amount of leverage.
+	Leverage string `json:"leverage"`
+	// Order description.
+	Order string `json:"order"`
+	// Conditional close order description (if conditional close set).
+	Close string `json:"close"`
+}
+
+// AddOrderInfo contains the result of a placed order.
+type AddOrderInfo struct {
+	// Order description info.
+	Descr OrderDescription `json:"descr"`
+	// Transaction ids for the order (if order was added successfully).
+	TxID []string `json:"txid"`
+}
+
+// EditOrderRequest contains the parameters amending an open order.
+// The empty parameters are left unchanged.
+type EditOrderRequest struct {
+	// Transaction id of the order to edit, or its user reference id.
+	TxID string
+	// Asset pair of the order, either its key or its altname.
+	Pair string
+	// New user reference id (optional), zero meaning unchanged.
+	UserRef int32
+	// New order volume in lots (optional).
+	Volume string
+	// New price (optional).
+	Price string
+	// New secondary price (optional).
+	Price2 string
+	// New order flags (optional): post, fcib, fciq. Replaces the order flags.
+	OFlags []string
+	// RFC3339 timestamp after which the matching engine should reject the
+	// new order request (optional).
+	Deadline string
+	// Whether the new order is sent even if the cancellation of the old one
+	// is pending.
+	CancelResponse bool
+	// Validate inputs only, do not submit the edit.
+	ValidateOnly bool
+}
+
+// Validate checks the order against the pair's scaling decimals and leverage
+// lists, and the consistency of its parameters, before it is sent.
+func (o *AddOrderRequest) Validate(pair *AssetPairInfo) error {
+	if o.Pair == "" {
+		return errors.New("JSON Error: Parameter pair cannot be empty")
+	}
+	if o.Type != OrderBuy && o.Type != OrderSell {
+		return fmt.Errorf("JSON Error: Invalid order type %q", o.Type)
+	}
+	if !isOrderType(o.OrderType) {
+		return fmt.Errorf("JSON Error: Invalid ordertype %q", o.OrderType)
+	}
+
+	flags := map[string]bool{}
+	for _, f := range o.OFlags {
+		switch f {
+		case OrderFlagPost, OrderFlagFeeInBase, OrderFlagFeeInQuote,
+			OrderFlagNoMarketPriceProtection, OrderFlagVolumeInQuote:
+			flags[f] = true
+		default:
+			return fmt.Errorf("JSON Error: Invalid oflag %q", f)
+		}
+	}
+	if flags[OrderFlagFeeInBase] && flags[OrderFlagFeeInQuote] {
+		return errors.New("JSON Error: Order flags fcib and fciq are exclusive")
+	}
+	if flags[OrderFlagPost] && o.OrderType != OrderTypeLimit {
+		return errors.New("JSON Error: Order flag post is only valid for limit orders")
+	}
+
+	volumeDecimals := pair.LotDecimals
+	if flags[OrderFlagVolumeInQuote] {
+		volumeDecimals = pair.PairDecimals
+	}
+	leveraged := o.Leverage != "" && o.Leverage != "none"
+	// a zero volume closes the whole margin position
+	if !leveraged || !isZeroDecimal(o.Volume) {
+		if err := checkDecimal("volume", o.Volume, volumeDecimals); err != nil {
+			return err
+		}
+	}
+	if err := checkPrices(o.OrderType, o.Price, o.Price2, pair.PairDecimals); err != nil {
+		return err
+	}
+
+	if leveraged {
+		leverages := pair.LeverageBuy
+		if o.Type == OrderSell {
+			leverages = pair.LeverageSell
+		}
+		if err := checkLeverage(o.Leverage, leverages); err != nil {
+			return err
+		}
+	}
+
+	if o.Close != nil {
+		if !isOrderType(o.Close.OrderType) || o.Close.OrderType == OrderTypeMarket ||
+			o.Close.OrderType == OrderTypeSettlePosition {
+			return fmt.Errorf("JSON Error: Invalid close ordertype %q", o.Close.OrderType)
+		}
+		if err := checkPrices(o.Close.OrderType, o.Close.Price, o.Close.Price2, pair.PairDecimals); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the edit against the pair's scaling decimals and the
+// consistency of its parameters, before it is sent.
+func (o *EditOrderRequest) Validate(pair *AssetPairInfo) error {
+	if o.TxID == "" {
+		return errors.New("JSON Error: Parameter txid cannot be empty")
+	}
+	if o.Pair == "" {
+		return errors.New("JSON Error: Parameter pair cannot be empty")
+	}
+	for _, f := range o.OFlags {
+		switch f {
+		case OrderFlagPost, OrderFlagFeeInBase, OrderFlagFeeInQuote:
+		default:
+			return fmt.Errorf("JSON Error: Invalid oflag %q", f)
+		}
+	}
+	if o.Volume != "" {
+		if err := checkDecimal("volume", o.Volume, pair.LotDecimals); err != nil {
+			return err
+		}
+	}
+	if o.Price != "" {
+		if err := checkPrice("price", o.Price, pair.PairDecimals); err != nil {
+			return err
+		}
+	}
+	if o.Price2 != "" {
+		if err := checkPrice("price2", o.Price2, pair.PairDecimals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isOrderType reports whether t is one of the known order types.
+func isOrderType(t string) bool {
+	switch t {
+	case OrderTypeMarket, OrderTypeLimit, OrderTypeStopLoss, OrderTypeTakeProfit,
+		OrderTypeStopLossLimit, OrderTypeTakeProfitLimit, OrderTypeSettlePosition:
+		return true
+	}
+	return false
+}
+
+// checkPrices checks the presence and scaling of the prices the order type needs.
+func checkPrices(orderType, price, price2 string, decimals byte) error {
+	switch orderType {
+	case OrderTypeMarket, OrderTypeSettlePosition:
+		return nil
+	case OrderTypeStopLossLimit, OrderTypeTakeProfitLimit:
+		if err := checkPrice("price2", price2, decimals); err != nil {
+			return err
+		}
+	}
+	return checkPrice("price", price, decimals)
+}
+
+// checkPrice checks a price, which can be preceded by +, - or # for a
+// relative price. Percentages are not checked for scaling.
+func checkPrice(name, price string, decimals byte) error {
+	if price == "" {
+		return fmt.Errorf("JSON Error: Parameter %s cannot be empty", name)
+	}
+	if strings.IndexAny(price[:1], "+-#") == 0 {
+		price = price[1:]
+	}
+	if strings.HasSuffix(price, "%") {
+		price = strings.TrimSuffix(price, "%")
+		decimals = 255
+	}
+	return checkDecimal(name, price, decimals)
+}
+
+// checkDecimal checks value is a positive decimal, digits[.digits], with at
+// most the given number of decimal places.
+func checkDecimal(name, value string, decimals byte) error {
+	if value == "" {
+		return fmt.Errorf("JSON Error: Parameter %s cannot be empty", name)
+	}
+	integer, fraction := value, ""
+	i := strings.IndexByte(value, '.')
+	if i >= 0 {
+		integer, fraction = value[:i], value[i+1:]
+	}
+	if !isDigits(integer) || (i >= 0 && !isDigits(fraction)) || strings.Trim(integer+fraction, "0") == "" {
+		return fmt.Errorf("JSON Error: Parameter %s must be a positive decimal, got %q", name, value)
+	}
+	if len(fraction) > int(decimals) {
+		return fmt.Errorf("JSON Error: Parameter %s %s has more than %d decimals", name, value, decimals)
+	}
+	return nil
+}
+
+// isZeroDecimal reports whether value is a decimal number equal to 0,
+// e.g. 0 or 0.000.
+func isZeroDecimal(value string) bool {
+	integer, fraction := value, "0"
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		integer, fraction = value[:i], value[i+1:]
+	}
+	return isDigits(integer) && isDigits(fraction) && strings.Trim(integer+fraction, "0") == ""
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkLeverage checks leverage, e.g. 2 or 2:1, is one of the allowed amounts.
+func checkLeverage(leverage string, allowed []byte) error {
+	n, err := strconv.Atoi(strings.SplitN(leverage, ":", 2)[0])
+	if err == nil {
+		for _, a := range allowed {
+			if int(a) == n {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("JSON Error: Leverage %s is not available, allowed: %v", leverage, allowed)
+}
+
+// EditOrderInfo contains the result of an order edit.
+type EditOrderInfo struct {
+	// Order description info.
+	Descr OrderDescription `json:"descr"`
+	// Transaction id of the new order.
+	TxID string `json:"txid"`
+	// Transaction id of the original order.
+	OriginalTxID string `json:"originaltxid"`
+	// Volume of the new order.
+	Volume string `json:"volume"`
+	// Price of the new order.
+	Price string `json:"price"`
+	// Secondary price of the new order.
+	Price2 string `json:"price2"`
+	// Number of orders cancelled (0 or 1).
+	OrdersCancelled int `json:"orders_cancelled"`
+	// Status of the edit: ok or err.
+	Status string `json:"status"`
+	// Error message if the edit failed.
+	ErrorMessage string `json:"error_message"`
+}
+
+// BatchOrderInfo contains the result of one order of a batch.
+type BatchOrderInfo struct {
+	// Order description info.
+	Descr OrderDescription `json:"descr"`
+	// Transaction id of the order (if order was added successfully).
+	TxID string `json:"txid"`
+	// Error message of the order (if it failed).
+	Error string `json:"error"`
+}
+
+// AddOrderBatchInfo contains the results of the orders of a batch,
+// in the order they were sent.
+type AddOrderBatchInfo struct {
+	Orders []BatchOrderInfo `json:"orders"`
+}
+
+// CancelOrderInfo contains the result of an order cancellation.
+type CancelOrderInfo struct {
+	// Number of orders canceled.
+	Count int `json:"count"`
+	// If set, order(s) is/are pending cancellation.
+	Pending bool `json:"pending"`
+}
+
+// CancelAfterInfo contains the state of the dead man's switch.
+type CancelAfterInfo struct {
+	// Timestamp at which the request was received.
+	CurrentTime time.Time `json:"currentTime"`
+	// Timestamp after which all orders will be cancelled, zero if the timer was disabled.
+	TriggerTime time.Time `json:"triggerTime"`
+}
+
+// UnmarshalJSON of the CancelAfterInfo, the trigger time being "0"
+// when the timer was disabled.
+func (c *CancelAfterInfo) UnmarshalJSON(b []byte) error {
+	var tmp struct {
+		CurrentTime string `json:"currentTime"`
+		TriggerTime string `json:"triggerTime"`
+	}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	var err error
+	if c.CurrentTime, err = time.Parse(time.RFC3339, tmp.CurrentTime); err != nil {
+		return err
+	}
+	c.TriggerTime = time.Time{}
+	if tmp.TriggerTime != "" && tmp.TriggerTime != "0" {
+		if c.TriggerTime, err = time.Parse(time.RFC3339, tmp.TriggerTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Order contains the info of an open or closed order.
+type Order struct {
+	// Transaction id of the order.
+	TxID string `json:"-"`
+	// Referral order transaction id that created this order.
+	RefID string `json:"refid"`
+	// User reference id.
+	UserRef int32 `json:"userref"`
+	// Status of order: pending, open, closed, canceled, expired.
+	Status string `json:"status"`
+	// Additional info on status (if any).
+	Reason string `json:"reason"`
+	// Time order was placed.
+	OpenTime time.Time `json:"opentm"`
+	// Order start time (zero if not set).
+	StartTime time.Time `json:"starttm"`
+	// Order end time (zero if not set).
+	ExpireTime time.Time `json:"expiretm"`
+	// Time order was closed (zero if still open).
+	CloseTime time.Time `json:"closetm"`
+	// Order description info.
+	Descr OrderDescription `json:"descr"`
+	// Volume of order (base currency unless viqc set in oflags).
+	Volume string `json:"vol"`
+	// Volume executed (base currency unless viqc set in oflags).
+	VolumeExec string `json:"vol_exec"`
+	// Total cost (quote currency unless viqc set in oflags).
+	Cost string `json:"cost"`
+	// Total fee (quote currency).
+	Fee string `json:"fee"`
+	// Average price (quote currency unless viqc set in oflags).
+	Price string `json:"price"`
+	// Stop price (quote currency, for trailing stops).
+	StopPrice string `json:"stopprice"`
+	// Triggered limit price (quote currency, when limit based order type triggered).
+	LimitPrice string `json:"limitprice"`
+	// Comma delimited list of miscellaneous info:
+	// stopped, touched, liquidated, partial.
+	Misc string `json:"misc"`
+	// Comma delimited list of order flags.
+	OFlags string `json:"oflags"`
+	// Array of trade ids related to order (if trades info requested and data available).
+	Trades []string `json:"trades"`
+}
+
+// UnmarshalJSON of the Order, converting its fractional unix times.
+func (o *Order) UnmarshalJSON(b []byte) error {
+	type order Order
+	tmp := struct {
+		*order
+		OpenTime   json.Number `json:"opentm"`
+		StartTime  json.Number `json:"starttm"`
+		ExpireTime json.Number `json:"expiretm"`
+		CloseTime  json.Number `json:"closetm"`
+	}{order: (*order)(o)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	if o.OpenTime, err = parseTimestamp(tmp.OpenTime); err != nil {
+		return err
+	}
+	if o.StartTime, err = parseTimestamp(tmp.StartTime); err != nil {
+		return err
+	}
+	if o.ExpireTime, err = parseTimestamp(tmp.ExpireTime); err != nil {
+		return err
+	}
+	if o.CloseTime, err = parseTimestamp(tmp.CloseTime); err != nil {
+		return err
+	}
+	return nil
+}
+
+// OrderMap maps transaction id to Order.
+type OrderMap map[string]Order
+
+// UnmarshalJSON of the OrderMap, setting the TxID of every order.
+func (m *OrderMap) UnmarshalJSON(b []byte) error {
+	var tmp map[string]Order
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	for txid, o := range tmp {
+		o.TxID = txid
+		tmp[txid] = o
+	}
+	*m = tmp
+	return nil
+}
+
+// Sorted returns the orders, the most recently opened first.
+func (m OrderMap) Sorted() []Order {
+	orders := make([]Order, 0, len(m))
+	for _, o := range m {
+		orders = append(orders, o)
+	}
+	sort.Sort(ordersByTime(orders))
+	return orders
+}
+
+// ordersByTime sorts the orders by descending open time, then transaction id.
+type ordersByTime []Order
+
+func (s ordersByTime) Len() int      { return len(s) }
+func (s ordersByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ordersByTime) Less(i, j int) bool {
+	if !s[i].OpenTime.Equal(s[j].OpenTime) {
+		return s[i].OpenTime.After(s[j].OpenTime)
+	}
+	return s[i].TxID < s[j].TxID
+}
+
+// OpenOrdersOptions contains the query parameters for the open orders request.
+type OpenOrdersOptions struct {
+	// Whether or not to include trades in output.
+	Trades bool
+	// Restrict results to given user reference id (optional), zero meaning none.
+	UserRef int32
+}
+
+// OpenOrdersInfo contains the open orders.
+type OpenOrdersInfo struct {
+	Open OrderMap `json:"open"`
+}
+
+// ClosedOrdersOptions contains the query parameters for the closed orders request.
+type ClosedOrdersOptions struct {
+	// Whether or not to include trades in output.
+	Trades bool
+	// Restrict results to given user reference id (optional), zero meaning none.
+	UserRef int32
+	// Starting unix timestamp or order tx id of results (optional. exclusive).
+	Start string
+	// Ending unix timestamp or order tx id of results (optional. inclusive).
+	End string
+	// Result offset.
+	Offset int
+	// Which time to use (optional): open, close, both (default).
+	CloseTime string
+}
+
+// ClosedOrdersInfo contains a page of closed orders.
+type ClosedOrdersInfo struct {
+	Closed OrderMap `json:"closed"`
+	// Amount of available order info matching criteria.
+	Count int `json:"count"`
+}
+
+// QueryOrdersOptions contains the query parameters for the query orders request.
+type QueryOrdersOptions struct {
+	// Whether or not to include trades in output.
+	Trades bool
+	// Restrict results to given user reference id (optional), zero meaning none.
+	UserRef int32
+}
+
+// TradeInfo contains the info of a trade of the account.
+type TradeInfo struct {
+	// Transaction id of the trade.
+	TxID string `json:"-"`
+	// Order responsible for execution of trade.
+	OrderTxID string `json:"ordertxid"`
+	// Position responsible for execution of trade (if any).
+	PosTxID string `json:"postxid"`
+	// Asset pair.
+	Pair string `json:"pair"`
+	// Time of trade.
+	Time time.Time `json:"time"`
+	// Type of order (buy/sell).
+	Type string `json:"type"`
+	// Order type.
+	OrderType string `json:"ordertype"`
+	// Average price order was executed at (quote currency).
+	Price string `json:"price"`
+	// Total cost of order (quote currency).
+	Cost string `json:"cost"`
+	// Total fee (quote currency).
+	Fee string `json:"fee"`
+	// Volume (base currency).
+	Volume string `json:"vol"`
+	// Initial margin (quote currency).
+	Margin string `json:"margin"`
+	// Comma delimited list of miscellaneous info: closing.
+	Misc string `json:"misc"`
+	// Position status (open/closed), if the trade opened a position.
+	PosStatus string `json:"posstatus"`
+	// Average price of closed portion of position (quote currency).
+	ClosedPrice string `json:"cprice"`
+	// Total cost of closed portion of position (quote currency).
+	ClosedCost string `json:"ccost"`
+	// Total fee of closed portion of position (quote currency).
+	ClosedFee string `json:"cfee"`
+	// Total volume of closed portion of position (quote currency).
+	ClosedVolume string `json:"cvol"`
+	// Total margin freed in closed portion of position (quote currency).
+	ClosedMargin string `json:"cmargin"`
+	// Net profit/loss of closed portion of position (quote currency, quote currency scale).
+	Net string `json:"net"`
+	// List of closing trades for position (if available).
+	Trades []string `json:"trades"`
+}
+
+// UnmarshalJSON of the TradeInfo, converting its fractional unix time.
+func (t *TradeInfo) UnmarshalJSON(b []byte) error {
+	type tradeInfo TradeInfo
+	tmp := struct {
+		*tradeInfo
+		Time json.Number `json:"time"`
+	}{tradeInfo: (*tradeInfo)(t)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	t.Time, err = parseTimestamp(tmp.Time)
+	return err
+}
+
+// TradeInfoMap maps transaction id to TradeInfo.
+type TradeInfoMap map[string]TradeInfo
+
+// UnmarshalJSON of the TradeInfoMap, setting the TxID of every trade.
+func (m *TradeInfoMap) UnmarshalJSON(b []byte) error {
+	var tmp map[string]TradeInfo
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	for txid, t := range tmp {
+		t.TxID = txid
+		tmp[txid] = t
+	}
+	*m = tmp
+	return nil
+}
+
+// Sorted returns the trades, the most recent first.
+func (m TradeInfoMap) Sorted() []TradeInfo {
+	trades := make([]TradeInfo, 0, len(m))
+	for _, t := range m {
+		trades = append(trades, t)
+	}
+	sort.Sort(tradesByTime(trades))
+	return trades
+}
+
+// tradesByTime sorts the trades by descending time, then transaction id.
+type tradesByTime []TradeInfo
+
+func (s tradesByTime) Len() int      { return len(s) }
+func (s tradesByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s tradesByTime) Less(i, j int) bool {
+	if !s[i].Time.Equal(s[j].Time) {
+		return s[i].Time.After(s[j].Time)
+	}
+	return s[i].TxID < s[j].TxID
+}
+
+// TradesHistoryOptions contains the query parameters for the trades history request.
+type TradesHistoryOptions struct {
+	// Type of trade (optional): all (default), any position, closed position,
+	// closing position, no position.
+	Type string
+	// Whether or not to include trades related to position in output.
+	Trades bool
+	// Starting unix timestamp or trade tx id of results (optional. exclusive).
+	Start string
+	// Ending unix timestamp or trade tx id of results (optional. inclusive).
+	End string
+	// Result offset.
+	Offset int
+}
+
+// TradesHistoryInfo contains a page of the trades history.
+type TradesHistoryInfo struct {
+	Trades TradeInfoMap `json:"trades"`
+	// Amount of available trades info matching criteria.
+	Count int `json:"count"`
+}
+
+// Position contains the info of an open margin position.
+type Position struct {
+	// Transaction id of the position.
+	TxID string `json:"-"`
+	// Order responsible for execution of trade.
+	OrderTxID string `json:"ordertxid"`
+	// Position status: open.
+	PosStatus string `json:"posstatus"`
+	// Asset pair.
+	Pair string `json:"pair"`
+	// Time of trade.
+	Time time.Time `json:"time"`
+	// Type of order used to open position (buy/sell).
+	Type string `json:"type"`
+	// Order type used to open position.
+	OrderType string `json:"ordertype"`
+	// Opening cost of position (quote currency unless viqc set in oflags).
+	Cost string `json:"cost"`
+	// Opening fee of position (quote currency).
+	Fee string `json:"fee"`
+	// Position volume (base currency unless viqc set in oflags).
+	Volume string `json:"vol"`
+	// Position volume closed (base currency unless viqc set in oflags).
+	VolumeClosed string `json:"vol_closed"`
+	// Initial margin (quote currency).
+	Margin string `json:"margin"`
+	// Current value of remaining position (if docalcs requested, quote currency).
+	Value string `json:"value"`
+	// Unrealized profit/loss of remaining position (if docalcs requested, quote currency).
+	Net string `json:"net"`
+	// Funding cost and term of position.
+	Terms string `json:"terms"`
+	// Time of next rollover.
+	RolloverTime time.Time `json:"rollovertm"`
+	// Comma delimited list of miscellaneous info.
+	Misc string `json:"misc"`
+	// Comma delimited list of order flags.
+	OFlags string `json:"oflags"`
+}
+
+// UnmarshalJSON of the Position, converting its unix times.
+func (p *Position) UnmarshalJSON(b []byte) error {
+	type position Position
+	tmp := struct {
+		*position
+		Time         json.Number `json:"time"`
+		RolloverTime json.Number `json:"rollovertm"`
+	}{position: (*position)(p)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	if p.Time, err = parseTimestamp(tmp.Time); err != nil {
+		return err
+	}
+	p.RolloverTime, err = parseTimestamp(tmp.RolloverTime)
+	return err
+}
+
+// PositionMap maps position transaction id to Position.
+type PositionMap map[string]Position
+
+// UnmarshalJSON of the PositionMap, setting the TxID of every position.
+func (m *PositionMap) UnmarshalJSON(b []byte) error {
+	var tmp map[string]Position
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	for txid, p := range tmp {
+		p.TxID = txid
+		tmp[txid] = p
+	}
+	*m = tmp
+	return nil
+}
+
+// LedgerEntry contains the info of a ledger entry of the account.
+type LedgerEntry struct {
+	// Ledger id.
+	ID string `json:"-"`
+	// Reference id.
+	RefID string `json:"refid"`
+	// Unix timestamp of ledger.
+	Time time.Time `json:"time"`
+	// Type of ledger entry.
+	Type string `json:"type"`
+	// Additional info relating to the ledger entry type, where applicable.
+	Subtype string `json:"subtype"`
+	// Asset class.
+	Aclass string `json:"aclass"`
+	// Asset id, as in AssetsInfoMap.
+	Asset string `json:"asset"`
+	// Transaction amount.
+	Amount string `json:"amount"`
+	// Transaction fee.
+	Fee string `json:"fee"`
+	// Resulting balance.
+	Balance string `json:"balance"`
+}
+
+// UnmarshalJSON of the LedgerEntry, converting its fractional unix time.
+func (l *LedgerEntry) UnmarshalJSON(b []byte) error {
+	type ledgerEntry LedgerEntry
+	tmp := struct {
+		*ledgerEntry
+		Time json.Number `json:"time"`
+	}{ledgerEntry: (*ledgerEntry)(l)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	l.Time, err = parseTimestamp(tmp.Time)
+	return err
+}
+
+// LedgerMap maps ledger id to LedgerEntry.
+type LedgerMap map[string]LedgerEntry
+
+// UnmarshalJSON of the LedgerMap, setting the ID of every entry.
+func (m *LedgerMap) UnmarshalJSON(b []byte) error {
+	var tmp map[string]LedgerEntry
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+	for id, l := range tmp {
+		l.ID = id
+		tmp[id] = l
+	}
+	*m = tmp
+	return nil
+}
+
+// Sorted returns the ledger entries, the most recent first.
+func (m LedgerMap) Sorted() []LedgerEntry {
+	entries := make([]LedgerEntry, 0, len(m))
+	for _, l := range m {
+		entries = append(entries, l)
+	}
+	sort.Sort(ledgersByTime(entries))
+	return entries
+}
+
+// ledgersByTime sorts the ledger entries by descending time, then id.
+type ledgersByTime []LedgerEntry
+
+func (s ledgersByTime) Len() int      { return len(s) }
+func (s ledgersByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ledgersByTime) Less(i, j int) bool {
+	if !s[i].Time.Equal(s[j].Time) {
+		return s[i].Time.After(s[j].Time)
+	}
+	return s[i].ID < s[j].ID
+}
+
+// LedgersOptions contains the query parameters for the ledgers request.
+type LedgersOptions struct {
+	// Asset class (optional): currency (default).
+	AssetClass string
+	// List of assets to restrict output to (optional): all (default).
+	Assets []string
+	// Type of ledger to retrieve (optional): all (default), deposit, withdrawal,
+	// trade, margin, rollover, credit, transfer, settled, staking, sale.
+	Type string
+	// Starting unix timestamp or ledger id of results (optional. exclusive).
+	Start string
+	// Ending unix timestamp or ledger id of results (optional. inclusive).
+	End string
+	// Result offset.
+	Offset int
+}
+
+// LedgersInfo contains a page of ledger entries.
+type LedgersInfo struct {
+	Ledger LedgerMap `json:"ledger"`
+	// Amount of available ledger info matching criteria.
+	Count int `json:"count"`
+}
+
+// FeeTierInfo contains the fee tier of the account for a pair.
+type FeeTierInfo struct {
+	// Current fee in percent.
+	Fee string `json:"fee"`
+	// Minimum fee for pair (if not fixed fee).
+	MinFee string `json:"minfee"`
+	// Maximum fee for pair (if not fixed fee).
+	MaxFee string `json:"maxfee"`
+	// Next tier's fee for pair (if not fixed fee, empty if at lowest fee tier).
+	NextFee string `json:"nextfee"`
+	// Volume level of next tier (if not fixed fee, empty if at lowest fee tier).
+	NextVolume string `json:"nextvolume"`
+	// Volume level of current tier (if not fixed fee, empty if at lowest fee tier).
+	TierVolume string `json:"tiervolume"`
+}
+
+// TradeVolumeInfo contains the 30 days trade volume and the fee tiers of the account.
+type TradeVolumeInfo struct {
+	// Volume currency.
+	Currency string `json:"currency"`
+	// Current discount volume.
+	Volume string `json:"volume"`
+	// Taker fee tier info, by pair (if requested).
+	Fees map[string]FeeTierInfo `json:"fees"`
+	// Maker fee tier info, by pair (if requested, for any pairs on maker/taker schedule).
+	FeesMaker map[string]FeeTierInfo `json:"fees_maker"`
+}
+
+// DepositMethod contains a deposit method of an asset.
+type DepositMethod struct {
+	// Name of deposit method.
+	Method string `json:"method"`
+	// Maximum net amount that can be deposited right now, empty if no limit.
+	Limit string `json:"-"`
+	// Amount of fees that will be paid.
+	Fee string `json:"fee"`
+	// Amount of fees for setting up a new address (if any).
+	AddressSetupFee string `json:"address-setup-fee"`
+	// Whether or not a new address can be generated for the method.
+	GenAddress bool `json:"gen-address"`
+}
+
+// UnmarshalJSON of the DepositMethod, the limit being false when there is none.
+func (d *DepositMethod) UnmarshalJSON(b []byte) error {
+	type depositMethod DepositMethod
+	tmp := struct {
+		*depositMethod
+		Limit json.RawMessage `json:"limit"`
+	}{depositMethod: (*depositMethod)(d)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	d.Limit = ""
+	if len(tmp.Limit) == 0 || string(tmp.Limit) == "false" || string(tmp.Limit) == "null" {
+		return nil
+	}
+	var limit json.Number
+	if err := json.Unmarshal(tmp.Limit, &limit); err != nil {
+		return err
+	}
+	d.Limit = limit.String()
+	return nil
+}
+
+// DepositAddress contains a deposit address of an asset.
+type DepositAddress struct {
+	// Deposit address.
+	Address string `json:"address"`
+	// Expiration time, zero if not expiring.
+	ExpireTime time.Time `json:"expiretm"`
+	// Whether or not address has ever been used.
+	New bool `json:"new"`
+	// Destination tag or memo of the address, for the assets needing one.
+	Tag string `json:"tag"`
+}
+
+// UnmarshalJSON of the DepositAddress, converting its unix time.
+func (d *DepositAddress) UnmarshalJSON(b []byte) error {
+	type depositAddress DepositAddress
+	tmp := struct {
+		*depositAddress
+		ExpireTime json.Number `json:"expiretm"`
+	}{depositAddress: (*depositAddress)(d)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	d.ExpireTime, err = parseTimestamp(tmp.ExpireTime)
+	return err
+}
+
+// TransferStatus contains the status of a recent deposit or withdrawal.
+type TransferStatus struct {
+	// Name of the deposit or withdrawal method used.
+	Method string `json:"method"`
+	// Asset class.
+	Aclass string `json:"aclass"`
+	// Asset id, as in AssetsInfoMap.
+	Asset string `json:"asset"`
+	// Reference id.
+	RefID string `json:"refid"`
+	// Method transaction id.
+	TxID string `json:"txid"`
+	// Method transaction information.
+	Info string `json:"info"`
+	// Amount deposited or withdrawn.
+	Amount string `json:"amount"`
+	// Fees paid.
+	Fee string `json:"fee"`
+	// Unix timestamp when request was made.
+	Time time.Time `json:"time"`
+	// Status of the transfer: Initial, Pending, Settled, Success, Failure.
+	Status string `json:"status"`
+	// Additional status property (if available): return, onhold,
+	// cancel-pending, canceled.
+	StatusProp string `json:"status-prop"`
+}
+
+// UnmarshalJSON of the TransferStatus, converting its unix time.
+func (t *TransferStatus) UnmarshalJSON(b []byte) error {
+	type transferStatus TransferStatus
+	tmp := struct {
+		*transferStatus
+		Time json.Number `json:"time"`
+	}{transferStatus: (*transferStatus)(t)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	t.Time, err = parseTimestamp(tmp.Time)
+	return err
+}
+
+// WithdrawInfo contains the limit and fee of a withdrawal.
+type WithdrawInfo struct {
+	// Name of the withdrawal method that will be used.
+	Method string `json:"method"`
+	// Maximum net amount that can be withdrawn right now.
+	Limit string `json:"limit"`
+	// Net amount that will be sent, after fees.
+	Amount string `json:"amount"`
+	// Amount of fees that will be paid.
+	Fee string `json:"fee"`
+}
+
+// WithdrawRequest contains the parameters of a withdrawal.
+type WithdrawRequest struct {
+	// Asset being withdrawn, as in AssetsInfoMap.
+	Asset string
+	// Withdrawal key name, as set up on the account.
+	Key string
+	// Amount to withdraw, including fees.
+	Amount string
+	// Confirmed must be set for the withdrawal to be sent. It is left unset
+	// in dry-run code paths, where Withdraw then fails with
+	// ErrWithdrawNotConfirmed.
+	Confirmed bool
+}
+
+// WithdrawReference contains the reference of a withdrawal.
+type WithdrawReference struct {
+	// Reference id.
+	RefID string `json:"refid"`
+}
+
+// StakingReward contains the reward earned while staking an asset.
+type StakingReward struct {
+	// Reward earned while staking.
+	Reward string `json:"reward"`
+	// Reward type: percentage.
+	Type string `json:"type"`
+}
+
+// StakingMinimum contains the minimum amounts for staking and unstaking an asset.
+type StakingMinimum struct {
+	Staking   string `json:"staking"`
+	Unstaking string `json:"unstaking"`
+}
+
+// StakingAsset contains the staking info of a stakeable asset.
+type StakingAsset struct {
+	// Unique id of the staking option, used in the Stake call.
+	Method string `json:"method"`
+	// Asset code, as in AssetsInfoMap.
+	Asset string `json:"asset"`
+	// Staking asset code, as in AssetsInfoMap.
+	StakingAsset string `json:"staking_asset"`
+	// Describes the rewards earned while staking.
+	Rewards StakingReward `json:"rewards"`
+	// Whether the staking operation is on-chain or not.
+	OnChain bool `json:"on_chain"`
+	// Whether the user will be able to stake this asset.
+	CanStake bool `json:"can_stake"`
+	// Whether the user will be able to unstake this asset.
+	CanUnstake bool `json:"can_unstake"`
+	// Minimum amounts for staking and unstaking.
+	MinimumAmount StakingMinimum `json:"minimum_amount"`
+}
+
+// StakingReference contains the reference of a staking or unstaking transaction.
+type StakingReference struct {
+	// Reference id of the transaction.
+	RefID string `json:"refid"`
+}
+
+// StakingTransaction contains the info of a staking transaction.
+type StakingTransaction struct {
+	// Staking method.
+	Method string `json:"method"`
+	// Asset class.
+	Aclass string `json:"aclass"`
+	// Asset code, as in AssetsInfoMap.
+	Asset string `json:"asset"`
+	// Reference id.
+	RefID string `json:"refid"`
+	// Amount of the transaction.
+	Amount string `json:"amount"`
+	// Fee of the transaction.
+	Fee string `json:"fee"`
+	// Time of the transaction.
+	Time time.Time `json:"time"`
+	// Status of the transaction: Initial, Pending, Settled, Success, Failure.
+	Status string `json:"status"`
+	// Type of the transaction: bonding, reward, unbonding.
+	Type string `json:"type"`
+	// Start of the bonding period (zero if not bonding).
+	BondStart time.Time `json:"bond_start"`
+	// End of the bonding period (zero if not bonding).
+	BondEnd time.Time `json:"bond_end"`
+}
+
+// UnmarshalJSON of the StakingTransaction, converting its unix times.
+func (t *StakingTransaction) UnmarshalJSON(b []byte) error {
+	type stakingTransaction StakingTransaction
+	tmp := struct {
+		*stakingTransaction
+		Time      json.Number `json:"time"`
+		BondStart json.Number `json:"bond_start"`
+		BondEnd   json.Number `json:"bond_end"`
+	}{stakingTransaction: (*stakingTransaction)(t)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	var err error
+	if t.Time, err = parseTimestamp(tmp.Time); err != nil {
+		return err
+	}
+	if t.BondStart, err = parseTimestamp(tmp.BondStart); err != nil {
+		return err
+	}
+	t.BondEnd, err = parseTimestamp(tmp.BondEnd)
+	return err
+}
+
+// WebSocketsToken is the token authenticating a private WebSocket connection.
+type WebSocketsToken struct {
+	// Token to subscribe to the private WebSocket feeds with.
+	Token string `json:"token"`
+	// Seconds the token is valid for, if not used to establish a connection.
+	Expires int `json:"expires"`
+	// Time the token expires at, if not used to establish a connection.
+	ExpireTime time.Time `json:"-"`
+}
+
+// AddExportRequest contains the parameters of a new data export report.
+type AddExportRequest struct {
+	// Type of data to export: trades or ledgers.
+	Report string
+	// File format to export: CSV (default) or TSV.
+	Format string
+	// Description of the report.
+	Description string
+	// Fields to include in the report (optional): all (default).
+	Fields []string
+	// Data starting time (optional): zero meaning one year before now.
+	StartTime time.Time
+	// Data ending time (optional): zero meaning now.
+	EndTime time.Time
+}
+
+// ExportReport contains the status of a data export report.
+type ExportReport struct {
+	// Report id.
+	ID string `json:"id"`
+	// Report description.
+	Descr string `json:"descr"`
+	// File format of the report.
+	Format string `json:"format"`
+	// Type of report: trades or ledgers.
+	Report string `json:"report"`
+	// Report subtype.
+	Subtype string `json:"subtype"`
+	// Status of the report: Queued, Processing, Processed.
+	Status string `json:"status"`
+	// Comma delimited list of the fields in the report.
+	Fields string `json:"fields"`
+	// Time the report was requested.
+	CreatedTime time.Time `json:"createdtm"`
+	// Time the report expires.
+	ExpireTime time.Time `json:"expiretm"`
+	// Time the report processing started.
+	StartTime time.Time `json:"starttm"`
+	// Time the report was completed.
+	CompletedTime time.Time `json:"completedtm"`
+	// Time of the first data in the report.
+	DataStartTime time.Time `json:"datastarttm"`
+	// Time of the last data in the report.
+	DataEndTime time.Time `json:"dataendtm"`
+	// Asset class.
+	Aclass string `json:"aclass"`
+	// Comma delimited list of the assets in the report.
+	Asset string `json:"asset"`
+}
+
+// UnmarshalJSON of the ExportReport, converting its unix times.
+func (e *ExportReport) UnmarshalJSON(b []byte) error {
+	type exportReport ExportReport
+	tmp := struct {
+		*exportReport
+		CreatedTime   json.Number `json:"createdtm"`
+		ExpireTime    json.Number `json:"expiretm"`
+		StartTime     json.Number `json:"starttm"`
+		CompletedTime json.Number `json:"completedtm"`
+		DataStartTime json.Number `json:"datastarttm"`
+		DataEndTime   json.Number `json:"dataendtm"`
+	}{exportReport: (*exportReport)(e)}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return err
+	}
+
+	times := []struct {
+		t *time.Time
+		n json.Number
+	}{
+		{&e.CreatedTime, tmp.CreatedTime},
+		{&e.ExpireTime, tmp.ExpireTime},
+		{&e.StartTime, tmp.StartTime},
+		{&e.CompletedTime, tmp.CompletedTime},
+		{&e.DataStartTime, tmp.DataStartTime},
+		{&e.DataEndTime, tmp.DataEndTime},
+	}
+	for _, v := range times {
+		var err error
+		if *v.t, err = parseTimestamp(v.n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveExportInfo contains the result of a report removal.
+type RemoveExportInfo struct {
+	// Whether the report was deleted.
+	Delete bool `json:"delete"`
+	// Whether the report was cancelled.
+	Cancel bool `json:"cancel"`
+}
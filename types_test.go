@@ -20,6 +20,53 @@ func Test_OHLCEntry_UnmarshalJSON(t *testing.T) {
 	if !data.Timestamp.Equal(time.Unix(1493786460, 0)) {
 		t.Errorf("data.Timestamp expected: 1493786460, got: %d", data.Timestamp.Unix())
 	}
+
+	columns := []struct {
+		name     string
+		value    Decimal
+		expected string
+	}{
+		{"Open", data.Open, "1326.860"},
+		{"High", data.High, "1326.880"},
+		{"Low", data.Low, "1324.533"},
+		{"Close", data.Close, "1326.880"},
+		{"VWAP", data.VWAP, "1326.643"},
+		{"Volume", data.Volume, "3.93936569"},
+	}
+	for _, c := range columns {
+		if c.value.String() != c.expected {
+			t.Errorf("data.%s expected: %s, got: %s", c.name, c.expected, c.value)
+		}
+	}
+
+	for _, invalid := range []string{
+		`["x","1326.860","1326.880","1324.533","1326.880","1326.643","3.93936569",9]`,
+		`[1493786460,"1326,860","1326.880","1324.533","1326.880","1326.643","3.93936569",9]`,
+		`{"open":"1326.860"}`,
+		`[1493786460,"1326.860","1326.880","1324.533","1326.880","1326.643","3.93936569"]`,
+		`[1493786460,"1326.860","1326.880","1324.533","1326.880","1326.643","3.93936569",9,0]`,
+		`[]`,
+	} {
+		if err := json.Unmarshal([]byte(invalid), &data); err == nil {
+			t.Errorf("%s should not be accepted", invalid)
+		}
+	}
+}
+
+func Test_OHLCEntry_MarshalJSON(t *testing.T) {
+	const incomingJSON = `[1493786460,"1326.860","1326.880","1324.533","1326.880","1326.643","3.93936569",9]`
+	var data OHLCEntry
+	if err := json.Unmarshal([]byte(incomingJSON), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != incomingJSON {
+		t.Errorf("OHLCEntry expected: %s, got: %s", incomingJSON, b)
+	}
 }
 
 func Test_TradeData_UnmarshalJSON(t *testing.T) {
@@ -32,10 +79,10 @@ func Test_TradeData_UnmarshalJSON(t *testing.T) {
 		t.Error(err)
 		t.Fail()
 	}
-	if data.Price != "1425.26000" {
+	if data.Price.String() != "1425.26000" {
 		t.Errorf("data.Price expected: 1425.26000, got: %s", data.Price)
 	}
-	if data.Volume != "8.96796823" {
+	if data.Volume.String() != "8.96796823" {
 		t.Errorf("data.Volume expected: 8.96796823, got: %s", data.Volume)
 	}
 
@@ -46,3 +93,95 @@ func Test_TradeData_UnmarshalJSON(t *testing.T) {
 	}
 
 }
+
+func Test_AssetPairInfo_FeeForVolume(t *testing.T) {
+	pair := AssetPairInfo{
+		Fees:      []FeeInfo{{0, 0.26}, {50000, 0.24}, {100000, 0.22}},
+		FeesMaker: []FeeInfo{{0, 0.16}, {50000, 0.14}, {100000, 0.12}},
+	}
+	testCases := []struct {
+		volume   float64
+		maker    bool
+		expected float32
+	}{
+		{0, false, 0.26},
+		{49999.99, false, 0.26},
+		{50000, false, 0.24},
+		{250000, false, 0.22},
+		{75000, true, 0.14},
+	}
+	for _, tc := range testCases {
+		if got := pair.FeeForVolume(tc.volume, tc.maker); got != tc.expected {
+			t.Errorf("Fee for volume %v (maker: %v) expected: %v, got: %v", tc.volume, tc.maker, tc.expected, got)
+		}
+	}
+
+	pair.FeesMaker = nil
+	if got := pair.FeeForVolume(0, true); got != 0.26 {
+		t.Errorf("Pairs not on maker/taker should use the fees schedule, got: %v", got)
+	}
+}
+
+func Test_TickerInfo_UnmarshalJSON(t *testing.T) {
+	const incomingJSON = `{"a":["1425.26000","1","1.000"],"b":["1425.00000","3","3.000"],"c":["1425.26000","8.96796823"],
+	"v":["2634.96","7146.22"],"p":["1418.63","1409.43"],"t":[4911,12934],"l":["1401.00000","1393.00000"],
+	"h":["1430.00000","1430.50000"],"o":"1407.98100"}`
+	var ticker TickerInfo
+	if err := json.Unmarshal([]byte(incomingJSON), &ticker); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := []struct {
+		name     string
+		value    Decimal
+		expected string
+	}{
+		{"Ask.Price", ticker.Ask.Price, "1425.26000"},
+		{"Ask.WholeLotVolume", ticker.Ask.WholeLotVolume, "1"},
+		{"Ask.LotVolume", ticker.Ask.LotVolume, "1.000"},
+		{"Bid.Price", ticker.Bid.Price, "1425.00000"},
+		{"Bid.WholeLotVolume", ticker.Bid.WholeLotVolume, "3"},
+		{"Bid.LotVolume", ticker.Bid.LotVolume, "3.000"},
+		{"LastTrade.Price", ticker.LastTrade.Price, "1425.26000"},
+		{"LastTrade.LotVolume", ticker.LastTrade.LotVolume, "8.96796823"},
+		{"Volume.Today", ticker.Volume.Today, "2634.96"},
+		{"Volume.Last24h", ticker.Volume.Last24h, "7146.22"},
+		{"VWAP.Today", ticker.VWAP.Today, "1418.63"},
+		{"VWAP.Last24h", ticker.VWAP.Last24h, "1409.43"},
+		{"Low.Today", ticker.Low.Today, "1401.00000"},
+		{"Low.Last24h", ticker.Low.Last24h, "1393.00000"},
+		{"High.Today", ticker.High.Today, "1430.00000"},
+		{"High.Last24h", ticker.High.Last24h, "1430.50000"},
+		{"Open", ticker.Open, "1407.98100"},
+		{"Raw.A[0]", ticker.Raw.A[0], "1425.26000"},
+	}
+	for _, f := range fields {
+		if f.value.String() != f.expected {
+			t.Errorf("ticker.%s expected: %s, got: %s", f.name, f.expected, f.value)
+		}
+	}
+	if ticker.TradeCount != (TickerCounts{Today: 4911, Last24h: 12934}) {
+		t.Errorf("ticker.TradeCount expected: {4911 12934}, got: %v", ticker.TradeCount)
+	}
+
+	b, err := json.Marshal(ticker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again TickerInfo
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if again.High.Last24h.String() != "1430.50000" || again.TradeCount.Last24h != 12934 {
+		t.Errorf("TickerInfo should be written back in the raw format, got: %s", b)
+	}
+
+	// missing values are left zero
+	if err := json.Unmarshal([]byte(`{"a":["1425.26000"],"t":[4911]}`), &ticker); err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Ask.Price.String() != "1425.26000" || !ticker.Ask.LotVolume.IsZero() || !ticker.Open.IsZero() ||
+		ticker.TradeCount.Last24h != 0 {
+		t.Errorf("Unexpected ticker from partial arrays: %+v", ticker)
+	}
+}